@@ -1,10 +1,82 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"media-downloader/internal/config"
+	"media-downloader/internal/media"
+	"media-downloader/internal/media/sources"
+	"media-downloader/internal/media/title"
+	"media-downloader/internal/media/ytdlp"
+	"media-downloader/internal/metrics"
 	"media-downloader/internal/www"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	log.Fatal(www.Initialize())
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	// Structured logs are easier to ingest in production
+	if cfg.LogFormat == "json" {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	}
+	www.SetLogger(slog.Default())
+
+	sources.SetAllowPrivateHosts(cfg.AllowPrivateHosts)
+	media.SetMaxDownloadBytes(uint64(cfg.MaxDownloadBytes))
+	if cfg.DownloadDir != "" {
+		media.SetDownloadDir(cfg.DownloadDir)
+	}
+	ytdlp.SetBinaryPath(cfg.YtdlpPath)
+	if err := media.SetProxy(cfg.YtdlpProxy); err != nil {
+		log.Fatalf("invalid YTDLP_PROXY: %v", err)
+	}
+	if err := ytdlp.SetCookiesFile(cfg.CookiesFile); err != nil {
+		log.Fatalf("invalid YTDLP_COOKIES: %v", err)
+	}
+	if err := ytdlp.SetCookiesDir(cfg.CookiesDir); err != nil {
+		log.Fatalf("invalid YTDLP_COOKIES_DIR: %v", err)
+	}
+	ytdlp.SetTimeout(cfg.YtdlpTimeout)
+	ytdlp.SetConcurrency(int(cfg.YtdlpConcurrency), int(cfg.YtdlpDownloadConcurrency))
+	ytdlp.SetCache(cfg.YtdlpCacheTTL, int(cfg.YtdlpCacheSize), cfg.YtdlpCacheSlidingMaxAge)
+	defer ytdlp.StopCache()
+	metrics.RegisterCache("media_info", ytdlp.CacheStats)
+	ytdlp.SetRetry(int(cfg.YtdlpRetryAttempts), cfg.YtdlpRetryDelay)
+
+	if cfg.TitlePatternsFile != "" {
+		patterns, err := title.LoadPatterns(cfg.TitlePatternsFile)
+		if err != nil {
+			log.Fatalf("failed to load title patterns: %v", err)
+		}
+
+		cleaner, err := title.NewCleaner(patterns)
+		if err != nil {
+			log.Fatalf("failed to load title patterns: %v", err)
+		}
+		media.SetTitleCleaner(cleaner)
+	}
+
+	// Stop accepting requests on SIGINT/SIGTERM, letting downloads finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Logged so extraction failures can be matched to an outdated yt-dlp
+	if version, err := ytdlp.Version(ctx); err != nil {
+		slog.Warn("yt-dlp version unknown", "error", err)
+	} else {
+		slog.Info("using yt-dlp", "version", version)
+	}
+
+	log.Printf("listening on %s", cfg.ListenAddr)
+	if err := www.Initialize(ctx, cfg); err != nil {
+		log.Fatal(err)
+	}
+	log.Print("server stopped")
 }
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Stats counts how well the cache is doing since it was created
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+
+	// Entries dropped to make room, and entries dropped for being too old
+	Evictions   uint64
+	Expirations uint64
+
+	Size int
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	created time.Time
+	expires time.Time
+}
+
+// TTLCache keeps values for a maximum age, evicting the least recently used
+// entry once it holds maxSize of them. It's safe to share between goroutines.
+type TTLCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	maxAge  time.Duration
+	maxSize int
+
+	// With a sliding ceiling every hit extends an entry by maxAge again, but
+	// never past the ceiling after it was set
+	slidingCeiling time.Duration
+
+	// Most recently used entries are at the front
+	order   *list.List
+	entries map[K]*list.Element
+	stats   Stats
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// EnableSliding makes hits keep entries alive for another maxAge, up to
+// ceiling after they were set, zero turns it off again
+func (c *TTLCache[K, V]) EnableSliding(ceiling time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slidingCeiling = ceiling
+}
+
+// NewTTLCache creates a cache keeping values for maxAge, with at most maxSize
+// entries when maxSize is positive
+func NewTTLCache[K comparable, V any](maxAge time.Duration, maxSize int) *TTLCache[K, V] {
+	return &TTLCache[K, V]{
+		maxAge:  maxAge,
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+		stop:    make(chan struct{}),
+	}
+}
+
+func (c *TTLCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+
+	e := element.Value.(*entry[K, V])
+	now := time.Now()
+	if now.After(e.expires) {
+		c.remove(element)
+		c.stats.Expirations++
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+
+	if c.slidingCeiling > 0 {
+		e.expires = now.Add(c.maxAge)
+		if ceiling := e.created.Add(c.slidingCeiling); e.expires.After(ceiling) {
+			e.expires = ceiling
+		}
+	}
+
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// TTL returns how long the entry for key has left before it expires
+func (c *TTLCache[K, V]) TTL(key K) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return 0, false
+	}
+
+	remaining := time.Until(element.Value.(*entry[K, V]).expires)
+	if remaining <= 0 {
+		return 0, false
+	}
+	return remaining, true
+}
+
+func (c *TTLCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	expires := now.Add(c.maxAge)
+	if element, ok := c.entries[key]; ok {
+		e := element.Value.(*entry[K, V])
+		e.value = value
+		e.created = now
+		e.expires = expires
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, created: now, expires: expires})
+
+	if c.maxSize > 0 && c.order.Len() > c.maxSize {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *TTLCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+func (c *TTLCache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+// Len returns the number of entries, including expired ones not yet cleaned
+func (c *TTLCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// StartCleaner removes expired entries every interval in the background,
+// until Stop is called
+func (c *TTLCache[K, V]) StartCleaner(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				c.removeExpired()
+			}
+		}
+	}()
+}
+
+// Stop ends the background cleaner, the cache itself keeps working
+func (c *TTLCache[K, V]) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+func (c *TTLCache[K, V]) removeExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for _, element := range c.entries {
+		if now.After(element.Value.(*entry[K, V]).expires) {
+			c.remove(element)
+			c.stats.Expirations++
+		}
+	}
+}
+
+func (c *TTLCache[K, V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry[K, V]).key)
+}
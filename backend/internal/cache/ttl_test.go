@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLCacheExpires(t *testing.T) {
+	c := NewTTLCache[string, int](20*time.Millisecond, 0)
+	c.Set("a", 1)
+
+	if value, ok := c.Get("a"); !ok || value != 1 {
+		t.Fatalf("got %d, %v, want 1, true", value, ok)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if value, ok := c.Get("a"); ok {
+		t.Errorf("got expired value %d", value)
+	}
+	if got := c.Len(); got != 0 {
+		t.Errorf("got %d entries, want the expired one removed on access", got)
+	}
+}
+
+func TestTTLCacheSetRefreshes(t *testing.T) {
+	c := NewTTLCache[string, int](time.Hour, 0)
+	c.Set("a", 1)
+	c.Set("a", 2)
+
+	if value, ok := c.Get("a"); !ok || value != 2 {
+		t.Errorf("got %d, %v, want 2, true", value, ok)
+	}
+	if got := c.Len(); got != 1 {
+		t.Errorf("got %d entries, want 1", got)
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("got a deleted value")
+	}
+}
+
+func TestTTLCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewTTLCache[string, int](time.Hour, 2)
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	// Reading a makes b the least recently used
+	c.Get("a")
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b wasn't evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if got := c.Len(); got != 2 {
+		t.Errorf("got %d entries, want 2", got)
+	}
+}
+
+func TestTTLCacheCleaner(t *testing.T) {
+	c := NewTTLCache[string, int](time.Millisecond, 0)
+	c.StartCleaner(5 * time.Millisecond)
+	defer c.Stop()
+
+	c.Set("a", 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the cleaner never removed the expired entry")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Stopping twice is fine
+	c.Stop()
+}
+
+func TestTTLCacheTTL(t *testing.T) {
+	c := NewTTLCache[string, int](time.Hour, 0)
+	c.Set("a", 1)
+
+	remaining, ok := c.TTL("a")
+	if !ok || remaining <= 59*time.Minute || remaining > time.Hour {
+		t.Errorf("got %v, %v, want about an hour left", remaining, ok)
+	}
+	if remaining, ok := c.TTL("missing"); ok || remaining != 0 {
+		t.Errorf("got %v, %v for a missing key, want 0, false", remaining, ok)
+	}
+}
+
+func TestTTLCacheStats(t *testing.T) {
+	c := NewTTLCache[string, int](20*time.Millisecond, 1)
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("missing")
+
+	// Setting b evicts a, then b expires while waiting
+	c.Set("b", 2)
+	time.Sleep(30 * time.Millisecond)
+	c.Get("b")
+
+	want := Stats{Hits: 1, Misses: 2, Evictions: 1, Expirations: 1, Size: 0}
+	if got := c.Stats(); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestTTLCacheSliding(t *testing.T) {
+	c := NewTTLCache[string, int](100*time.Millisecond, 0)
+	c.EnableSliding(250 * time.Millisecond)
+	c.Set("a", 1)
+
+	// Each hit keeps the entry for another 100ms, past its original expiry
+	for range 3 {
+		time.Sleep(50 * time.Millisecond)
+		if _, ok := c.Get("a"); !ok {
+			t.Fatal("a expired despite being hit")
+		}
+	}
+
+	// But never past the ceiling after it was set
+	time.Sleep(150 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("a outlived the sliding ceiling")
+	}
+}
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	// Address the HTTP server listens on
+	ListenAddr string
+
+	// How long in-flight requests may take to finish when shutting down
+	ShutdownTimeout time.Duration
+
+	// Format of the log output, either text (default) or json
+	LogFormat string
+
+	// Requests per minute a single client IP may make to the endpoints that
+	// run yt-dlp, zero means unlimited, and how many at once
+	ClientRateLimit int64
+	ClientRateBurst int64
+
+	// Largest download in bytes that will be served, zero means unlimited
+	MaxDownloadBytes int64
+
+	// Comma separated origins allowed to call the API, "*" allows any origin
+	CORSOrigins string
+
+	// Aggregate cap on bytes per second across all downloads, zero means unlimited
+	MaxDownloadRate int64
+
+	// Directory server-side downloads are written to, a directory under the
+	// system's temporary directory by default
+	DownloadDir string
+
+	// Download jobs running at once, and how long finished jobs and their
+	// files are kept
+	JobWorkers   int64
+	JobResultTTL time.Duration
+
+	// Netscape formatted cookies file passed on to yt-dlp, empty disables cookies
+	CookiesFile string
+
+	// Directory of <name>.txt cookies files requests can pick with the
+	// X-Cookies-Profile header, empty disables per-request cookies. A file
+	// named after a source, like instagram.txt, is used for that source by default
+	CookiesDir string
+
+	// Template downloads are named by, such as "{uploader} - {title} [{id}]".
+	// Unknown placeholders are rejected at startup, the extension is always added
+	FilenameTemplate string
+
+	// File with one title cleaning regex per line, empty uses the built in defaults
+	TitlePatternsFile string
+
+	// Key naming of JSON responses, either snake_case (default) or camelCase
+	JSONNaming string
+
+	// Whether muxing and audio extraction, which need ffmpeg, are offered
+	FFmpegFeatures bool
+
+	// Whether the /api/debug endpoints are served, they expose unprocessed
+	// extractor output and must stay off in production
+	DebugEndpoints bool
+
+	// Whether media URLs may point at loopback and private network addresses
+	AllowPrivateHosts bool
+
+	// Path to the yt-dlp binary, looked up on the PATH by default
+	YtdlpPath string
+
+	// HTTP or SOCKS proxy all yt-dlp runs and direct fetches go through. When
+	// set it takes precedence over the HTTP_PROXY, HTTPS_PROXY and ALL_PROXY
+	// environment variables, which are only honored while it is empty. A proxy
+	// passed for a single request overrides both.
+	YtdlpProxy string
+
+	// Maximum number of yt-dlp processes extracting media info at once, zero
+	// uses the number of CPUs
+	YtdlpConcurrency int64
+
+	// Maximum number of yt-dlp processes downloading at once, zero leaves
+	// room for as many merged downloads as there are CPUs. Merged downloads
+	// run two processes so at least 2 is needed. Downloads have their own
+	// limit so they can't starve extraction.
+	YtdlpDownloadConcurrency int64
+
+	// How long extracted media info is reused, zero disables caching
+	YtdlpCacheTTL time.Duration
+
+	// Maximum number of cached extractions, the least recently used go first
+	YtdlpCacheSize int64
+
+	// When set, every cache hit keeps the extraction for another
+	// YtdlpCacheTTL, but never longer than this after it was extracted as
+	// the format URLs expire eventually. Zero keeps the fixed expiry.
+	YtdlpCacheSlidingMaxAge time.Duration
+
+	// Upper bound on a single yt-dlp metadata extraction
+	YtdlpTimeout time.Duration
+
+	// Attempts at a yt-dlp call failing transiently, and the delay before the
+	// first retry which doubles with every attempt
+	YtdlpRetryAttempts int64
+	YtdlpRetryDelay    time.Duration
+}
+
+func Load() (*Config, error) {
+	var err error
+	config := &Config{}
+
+	config.ListenAddr = getString("LISTEN_ADDR", ":8080")
+	if config.ShutdownTimeout, err = getDuration("SHUTDOWN_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+
+	config.CORSOrigins = getString("CORS_ORIGINS", "*")
+
+	config.LogFormat = getString("LOG_FORMAT", "text")
+	if config.LogFormat != "text" && config.LogFormat != "json" {
+		return nil, fmt.Errorf("LOG_FORMAT must be text or json, got %q", config.LogFormat)
+	}
+
+	if config.MaxDownloadRate, err = getInt64("MAX_DOWNLOAD_RATE", 0); err != nil {
+		return nil, err
+	}
+	if config.MaxDownloadRate < 0 {
+		return nil, fmt.Errorf("MAX_DOWNLOAD_RATE must not be negative")
+	}
+
+	if config.MaxDownloadBytes, err = getInt64("MAX_DOWNLOAD_BYTES", 0); err != nil {
+		return nil, err
+	}
+	if config.MaxDownloadBytes < 0 {
+		return nil, fmt.Errorf("MAX_DOWNLOAD_BYTES must not be negative")
+	}
+
+	if config.ClientRateLimit, err = getInt64("CLIENT_RATE_LIMIT", 0); err != nil {
+		return nil, err
+	}
+	if config.ClientRateLimit < 0 {
+		return nil, fmt.Errorf("CLIENT_RATE_LIMIT must not be negative")
+	}
+	if config.ClientRateBurst, err = getInt64("CLIENT_RATE_BURST", 10); err != nil {
+		return nil, err
+	}
+	if config.ClientRateBurst < 1 {
+		return nil, fmt.Errorf("CLIENT_RATE_BURST must be at least 1")
+	}
+
+	config.DownloadDir = getString("DOWNLOAD_DIR", "")
+	if config.JobWorkers, err = getInt64("JOB_WORKERS", 2); err != nil {
+		return nil, err
+	}
+	if config.JobWorkers < 1 {
+		return nil, fmt.Errorf("JOB_WORKERS must be at least 1")
+	}
+	if config.JobResultTTL, err = getDuration("JOB_RESULT_TTL", time.Hour); err != nil {
+		return nil, err
+	}
+	if config.JobResultTTL <= 0 {
+		return nil, fmt.Errorf("JOB_RESULT_TTL must be positive")
+	}
+
+	config.YtdlpPath = getString("YTDLP_PATH", "yt-dlp")
+	config.YtdlpProxy = getString("YTDLP_PROXY", "")
+	if err = validateProxy(config.YtdlpProxy); err != nil {
+		return nil, err
+	}
+	config.CookiesFile = getString("YTDLP_COOKIES", "")
+	config.CookiesDir = getString("YTDLP_COOKIES_DIR", "")
+	config.FilenameTemplate = getString("FILENAME_TEMPLATE", "{title}")
+	config.TitlePatternsFile = getString("TITLE_PATTERNS_FILE", "")
+	config.JSONNaming = getString("JSON_NAMING", "snake_case")
+
+	if config.FFmpegFeatures, err = getBool("FFMPEG_FEATURES", true); err != nil {
+		return nil, err
+	}
+	if config.AllowPrivateHosts, err = getBool("ALLOW_PRIVATE_HOSTS", false); err != nil {
+		return nil, err
+	}
+	if config.DebugEndpoints, err = getBool("DEBUG_ENDPOINTS", false); err != nil {
+		return nil, err
+	}
+
+	if config.YtdlpConcurrency, err = getInt64("YTDLP_CONCURRENCY", 0); err != nil {
+		return nil, err
+	}
+	if config.YtdlpConcurrency == 0 {
+		config.YtdlpConcurrency = int64(runtime.NumCPU())
+	}
+	if config.YtdlpConcurrency < 1 {
+		return nil, fmt.Errorf("YTDLP_CONCURRENCY must be at least 1")
+	}
+
+	if config.YtdlpDownloadConcurrency, err = getInt64("YTDLP_DOWNLOAD_CONCURRENCY", 0); err != nil {
+		return nil, err
+	}
+	if config.YtdlpDownloadConcurrency == 0 {
+		config.YtdlpDownloadConcurrency = 2 * int64(runtime.NumCPU())
+	}
+	if config.YtdlpDownloadConcurrency < 2 {
+		return nil, fmt.Errorf("YTDLP_DOWNLOAD_CONCURRENCY must be at least 2")
+	}
+
+	if config.YtdlpTimeout, err = getDuration("YTDLP_TIMEOUT", 60*time.Second); err != nil {
+		return nil, err
+	}
+	if config.YtdlpTimeout <= 0 {
+		return nil, fmt.Errorf("YTDLP_TIMEOUT must be positive")
+	}
+
+	if config.YtdlpCacheTTL, err = getDuration("YTDLP_CACHE_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if config.YtdlpCacheTTL < 0 {
+		return nil, fmt.Errorf("YTDLP_CACHE_TTL must not be negative")
+	}
+	if config.YtdlpCacheSize, err = getInt64("YTDLP_CACHE_SIZE", 256); err != nil {
+		return nil, err
+	}
+	if config.YtdlpCacheSize < 1 {
+		return nil, fmt.Errorf("YTDLP_CACHE_SIZE must be at least 1")
+	}
+	if config.YtdlpCacheSlidingMaxAge, err = getDuration("YTDLP_CACHE_SLIDING_MAX_AGE", 0); err != nil {
+		return nil, err
+	}
+	if config.YtdlpCacheSlidingMaxAge < 0 {
+		return nil, fmt.Errorf("YTDLP_CACHE_SLIDING_MAX_AGE must not be negative")
+	}
+
+	if config.YtdlpRetryAttempts, err = getInt64("YTDLP_RETRY_ATTEMPTS", 3); err != nil {
+		return nil, err
+	}
+	if config.YtdlpRetryAttempts < 1 {
+		return nil, fmt.Errorf("YTDLP_RETRY_ATTEMPTS must be at least 1")
+	}
+	if config.YtdlpRetryDelay, err = getDuration("YTDLP_RETRY_DELAY", time.Second); err != nil {
+		return nil, err
+	}
+	if config.YtdlpRetryDelay < 0 {
+		return nil, fmt.Errorf("YTDLP_RETRY_DELAY must not be negative")
+	}
+
+	return config, nil
+}
+
+// validateProxy accepts the proxy schemes supported by both yt-dlp and Go's
+// HTTP client, an empty value means no proxy
+func validateProxy(raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid value for YTDLP_PROXY: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("YTDLP_PROXY must use http, https, socks5 or socks5h, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("YTDLP_PROXY must include a host")
+	}
+	return nil
+}
+
+func getString(key string, def string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	return value
+}
+
+func getInt64(key string, def int64) (int64, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def, nil
+	}
+
+	intValue, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	return intValue, nil
+}
+
+func getBool(key string, def bool) (bool, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def, nil
+	}
+
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	return boolValue, nil
+}
+
+func getDuration(key string, def time.Duration) (time.Duration, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	return duration, nil
+}
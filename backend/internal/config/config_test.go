@@ -0,0 +1,45 @@
+package config
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestLoadYtdlpConcurrency(t *testing.T) {
+	tests := []struct {
+		name         string
+		extract      string
+		download     string
+		wantExtract  int64
+		wantDownload int64
+		wantErr      bool
+	}{
+		{name: "defaults", wantExtract: int64(runtime.NumCPU()), wantDownload: 2 * int64(runtime.NumCPU())},
+		{name: "configured", extract: "3", download: "4", wantExtract: 3, wantDownload: 4},
+		{name: "single extraction", extract: "1", download: "2", wantExtract: 1, wantDownload: 2},
+		{name: "negative extraction", extract: "-1", wantErr: true},
+		{name: "single download", download: "1", wantErr: true},
+		{name: "not a number", download: "many", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("YTDLP_CONCURRENCY", test.extract)
+			t.Setenv("YTDLP_DOWNLOAD_CONCURRENCY", test.download)
+
+			config, err := Load()
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got nil error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.YtdlpConcurrency != test.wantExtract || config.YtdlpDownloadConcurrency != test.wantDownload {
+				t.Errorf("got %d and %d, want %d and %d", config.YtdlpConcurrency, config.YtdlpDownloadConcurrency, test.wantExtract, test.wantDownload)
+			}
+		})
+	}
+}
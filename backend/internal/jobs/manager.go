@@ -0,0 +1,283 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"media-downloader/internal/progress"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrQueueFull = errors.New("too many jobs are queued")
+
+// How often finished jobs past their TTL are dropped
+const cleanInterval = time.Minute
+
+type State string
+
+const (
+	Queued    State = "queued"
+	Running   State = "running"
+	Done      State = "done"
+	Failed    State = "failed"
+	Cancelled State = "cancelled"
+)
+
+// Result is the file a job produced and the name to serve it under
+type Result struct {
+	Path     string
+	Filename string
+}
+
+// Work does the job, reporting its progress to the tracker in ctx
+type Work func(ctx context.Context) (Result, error)
+
+// Status is a point in time view of a job
+type Status struct {
+	ID       string            `json:"id"`
+	State    State             `json:"state"`
+	Progress progress.Snapshot `json:"progress"`
+	Error    string            `json:"error,omitempty"`
+}
+
+type Job struct {
+	id      string
+	work    Work
+	tracker *progress.Tracker
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	state    State
+	err      error
+	result   Result
+	finished time.Time
+}
+
+func (j *Job) ID() string {
+	return j.id
+}
+
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := Status{ID: j.id, State: j.state, Progress: j.tracker.Snapshot()}
+	if j.err != nil {
+		status.Error = j.err.Error()
+	}
+	return status
+}
+
+// Result returns the produced file once the job is done
+func (j *Job) Result() (Result, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.result, j.state == Done
+}
+
+// Manager runs jobs in the background with bounded concurrency, keeping
+// finished jobs and their files around for a while. It's safe to share
+// between goroutines.
+type Manager struct {
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	queue chan *Job
+	ttl   time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager starts workers running jobs at once, queueing up to queueSize
+// more. Finished jobs are forgotten and their files removed after ttl.
+func NewManager(workers int, queueSize int, ttl time.Duration) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		jobs:   make(map[string]*Job),
+		queue:  make(chan *Job, queueSize),
+		ttl:    ttl,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for range max(1, workers) {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	go m.cleaner()
+	return m
+}
+
+// Submit queues work as a new job
+func (m *Manager) Submit(work Work) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	tracker := progress.NewTracker()
+
+	ctx, cancel := context.WithCancel(progress.WithTracker(m.ctx, tracker))
+	job := &Job{id: id, work: work, tracker: tracker, ctx: ctx, cancel: cancel, state: Queued}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case m.queue <- job:
+	default:
+		cancel()
+		return nil, ErrQueueFull
+	}
+	m.jobs[id] = job
+	return job, nil
+}
+
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// Stop cancels running jobs, waits for the workers to exit and removes
+// every file produced
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, job := range m.jobs {
+		job.removeResult()
+		delete(m.jobs, id)
+	}
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case job := <-m.queue:
+			m.run(job)
+		}
+	}
+}
+
+// Cancel stops a queued or running job, killing whatever it runs, and
+// forgets a finished job along with its file. It reports whether the job
+// was known.
+func (m *Manager) Cancel(id string) bool {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	if ok && job.finish(Cancelled, Result{}, nil) {
+		m.mu.Unlock()
+		job.cancel()
+		return true
+	}
+	if ok {
+		delete(m.jobs, id)
+	}
+	m.mu.Unlock()
+
+	if ok {
+		job.removeResult()
+	}
+	return ok
+}
+
+func (m *Manager) run(job *Job) {
+	job.mu.Lock()
+	if job.state != Queued {
+		// Cancelled while waiting for a worker
+		job.mu.Unlock()
+		return
+	}
+	job.state = Running
+	job.mu.Unlock()
+
+	result, err := job.work(job.ctx)
+	job.cancel()
+
+	state := Done
+	if err != nil {
+		state = Failed
+	}
+	if !job.finish(state, result, err) && result.Path != "" {
+		// Cancelled just as the work completed, nobody will fetch the file
+		_ = os.Remove(result.Path)
+	}
+}
+
+// finish moves a job that hasn't finished yet into its final state,
+// reporting whether it did. Whichever of completion and cancellation comes
+// first wins.
+func (j *Job) finish(state State, result Result, err error) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.finished.IsZero() {
+		return false
+	}
+	j.state = state
+	j.result = result
+	j.err = err
+	j.finished = time.Now()
+	if state == Cancelled {
+		j.tracker.Finish(context.Canceled)
+	} else {
+		j.tracker.Finish(err)
+	}
+	return true
+}
+
+func (m *Manager) cleaner() {
+	ticker := time.NewTicker(cleanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.removeExpired()
+		}
+	}
+}
+
+func (m *Manager) removeExpired() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, job := range m.jobs {
+		job.mu.Lock()
+		expired := !job.finished.IsZero() && time.Since(job.finished) > m.ttl
+		job.mu.Unlock()
+
+		if expired {
+			job.removeResult()
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func (j *Job) removeResult() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.result.Path != "" {
+		_ = os.Remove(j.result.Path)
+	}
+}
+
+func newID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
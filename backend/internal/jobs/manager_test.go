@@ -0,0 +1,192 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForState polls until the job reaches state, failing after a while
+func waitForState(t *testing.T, job *Job, state State) Status {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := job.Status()
+		if status.State == state {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job stayed %s, want %s", status.State, state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManagerRunsJobs(t *testing.T) {
+	m := NewManager(2, 4, time.Hour)
+	defer m.Stop()
+
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	done, err := m.Submit(func(ctx context.Context) (Result, error) {
+		return Result{Path: path, Filename: "Test video.mp4"}, os.WriteFile(path, []byte("video"), 0o644)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failed, err := m.Submit(func(ctx context.Context) (Result, error) {
+		return Result{}, errors.New("video unavailable")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitForState(t, done, Done)
+	if result, ok := done.Result(); !ok || result.Path != path || result.Filename != "Test video.mp4" {
+		t.Errorf("got result %+v, %v", result, ok)
+	}
+
+	status := waitForState(t, failed, Failed)
+	if status.Error != "video unavailable" {
+		t.Errorf("got error %q, want %q", status.Error, "video unavailable")
+	}
+	if _, ok := failed.Result(); ok {
+		t.Error("got a result of a failed job")
+	}
+
+	if job, ok := m.Get(done.ID()); !ok || job != done {
+		t.Errorf("Get(%q) didn't return the job", done.ID())
+	}
+	if _, ok := m.Get("missing"); ok {
+		t.Error("got a job that was never submitted")
+	}
+}
+
+func TestManagerQueueFull(t *testing.T) {
+	m := NewManager(1, 1, time.Hour)
+
+	// Hold the only worker until the end of the test
+	release := make(chan struct{})
+	block := func(ctx context.Context) (Result, error) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return Result{}, nil
+	}
+	defer func() {
+		close(release)
+		m.Stop()
+	}()
+
+	running, err := m.Submit(block)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForState(t, running, Running)
+
+	if _, err := m.Submit(block); err != nil {
+		t.Fatalf("unexpected error queueing a job: %v", err)
+	}
+	if _, err := m.Submit(block); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("got error %v, want %v", err, ErrQueueFull)
+	}
+}
+
+func TestManagerRemovesFiles(t *testing.T) {
+	m := NewManager(1, 4, time.Hour)
+
+	dir := t.TempDir()
+	submit := func(name string) (*Job, string) {
+		path := filepath.Join(dir, name)
+		job, err := m.Submit(func(ctx context.Context) (Result, error) {
+			return Result{Path: path, Filename: name}, os.WriteFile(path, []byte(name), 0o644)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		waitForState(t, job, Done)
+		return job, path
+	}
+
+	expired, expiredPath := submit("expired.mp4")
+	_, keptPath := submit("kept.mp4")
+
+	// Only jobs finished longer than the TTL ago are dropped
+	expired.mu.Lock()
+	expired.finished = time.Now().Add(-2 * time.Hour)
+	expired.mu.Unlock()
+	m.removeExpired()
+
+	if _, ok := m.Get(expired.ID()); ok {
+		t.Error("the expired job is still known")
+	}
+	if _, err := os.Stat(expiredPath); !os.IsNotExist(err) {
+		t.Errorf("the expired job's file is still there: %v", err)
+	}
+	if _, err := os.Stat(keptPath); err != nil {
+		t.Errorf("the recent job's file is gone: %v", err)
+	}
+
+	// Stopping removes everything left
+	m.Stop()
+	if _, err := os.Stat(keptPath); !os.IsNotExist(err) {
+		t.Errorf("the file is still there after stopping: %v", err)
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	m := NewManager(1, 4, time.Hour)
+	defer m.Stop()
+
+	stopped := make(chan struct{})
+	running, err := m.Submit(func(ctx context.Context) (Result, error) {
+		<-ctx.Done()
+		close(stopped)
+		return Result{}, ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForState(t, running, Running)
+
+	// Waits behind the running job
+	queued, err := m.Submit(func(ctx context.Context) (Result, error) {
+		t.Error("a cancelled job ran")
+		return Result{}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !m.Cancel(queued.ID()) {
+		t.Error("the queued job wasn't known")
+	}
+	if !m.Cancel(running.ID()) {
+		t.Error("the running job wasn't known")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the running job's context was never cancelled")
+	}
+
+	for _, job := range []*Job{queued, running} {
+		if got := job.Status().State; got != Cancelled {
+			t.Errorf("got state %s, want %s", got, Cancelled)
+		}
+	}
+
+	// Cancelling a finished job forgets it
+	if !m.Cancel(running.ID()) {
+		t.Error("the cancelled job wasn't known")
+	}
+	if _, ok := m.Get(running.ID()); ok {
+		t.Error("the cancelled job is still known after cancelling it again")
+	}
+	if m.Cancel("missing") {
+		t.Error("cancelled a job that was never submitted")
+	}
+}
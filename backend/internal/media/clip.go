@@ -0,0 +1,44 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"media-downloader/internal/media/info"
+)
+
+// Clip limits a download to the section between Start and End, in seconds
+type Clip struct {
+	Start float64
+	End   float64
+}
+
+type clipKey struct{}
+
+// WithClip makes downloads started with the returned context only fetch the
+// given section of the media
+func WithClip(ctx context.Context, clip Clip) context.Context {
+	return context.WithValue(ctx, clipKey{}, clip)
+}
+
+func ClipFrom(ctx context.Context) (Clip, bool) {
+	clip, ok := ctx.Value(clipKey{}).(Clip)
+	return clip, ok
+}
+
+// checkClip makes sure a requested clip lies within the media
+func checkClip(ctx context.Context, mediaInfo *info.Media) error {
+	clip, ok := ClipFrom(ctx)
+	if !ok {
+		return nil
+	}
+
+	if clip.Start < 0 || clip.Start >= clip.End {
+		return fmt.Errorf("%w: clip start must be before its end", ErrInvalidRequest)
+	}
+
+	// Without a known duration there is nothing to check against
+	if mediaInfo.Duration > 0 && clip.End > mediaInfo.Duration {
+		return fmt.Errorf("%w: clip ends after the media's duration of %.0f seconds", ErrInvalidRequest, mediaInfo.Duration)
+	}
+	return nil
+}
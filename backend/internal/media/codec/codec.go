@@ -0,0 +1,199 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Details struct {
+	Family  string `json:"family"`
+	Profile string `json:"profile,omitempty"`
+	Level   string `json:"level,omitempty"`
+}
+
+// Parse extracts the codec family, profile and level from an RFC 6381 style
+// codec string such as "avc1.640028", "av01.0.05M.08" or "mp4a.40.2"
+func Parse(codec string) Details {
+	parts := strings.Split(strings.TrimSpace(codec), ".")
+	tag := strings.ToLower(parts[0])
+	params := parts[1:]
+
+	switch tag {
+	case "avc1", "avc3", "h264":
+		return parseAVC(params)
+	case "av01", "av1":
+		return parseAV1(params)
+	case "vp09", "vp9":
+		return parseVP9(params)
+	case "vp08", "vp8":
+		return Details{Family: "VP8"}
+	case "hvc1", "hev1", "h265", "hevc":
+		return parseHEVC(params)
+	case "mp4a":
+		return parseMP4A(params)
+	case "aac":
+		return Details{Family: "AAC"}
+	case "mp3":
+		return Details{Family: "MP3"}
+	case "opus":
+		return Details{Family: "Opus"}
+	case "vorbis":
+		return Details{Family: "Vorbis"}
+	case "flac":
+		return Details{Family: "FLAC"}
+	case "ac-3":
+		return Details{Family: "AC-3"}
+	case "ec-3":
+		return Details{Family: "E-AC-3"}
+	case "", "none":
+		return Details{}
+	default:
+		return Details{Family: parts[0]}
+	}
+}
+
+var avcProfiles = map[int64]string{
+	66:  "Baseline",
+	77:  "Main",
+	88:  "Extended",
+	100: "High",
+	110: "High 10",
+	122: "High 4:2:2",
+	244: "High 4:4:4",
+}
+
+func parseAVC(params []string) Details {
+	details := Details{Family: "H.264"}
+	if len(params) == 0 || len(params[0]) != 6 {
+		return details
+	}
+
+	// avc1.PPCCLL, profile and level are hex encoded
+	if profile, err := strconv.ParseInt(params[0][0:2], 16, 64); err == nil {
+		details.Profile = lookup(avcProfiles, profile)
+	}
+	if level, err := strconv.ParseInt(params[0][4:6], 16, 64); err == nil {
+		details.Level = decimalLevel(level, 10)
+	}
+
+	return details
+}
+
+var av1Profiles = map[int64]string{
+	0: "Main",
+	1: "High",
+	2: "Professional",
+}
+
+func parseAV1(params []string) Details {
+	details := Details{Family: "AV1"}
+
+	// av01.P.LLT.DD
+	if len(params) > 0 {
+		if profile, err := strconv.ParseInt(params[0], 10, 64); err == nil {
+			details.Profile = lookup(av1Profiles, profile)
+		}
+	}
+	if len(params) > 1 && len(params[1]) >= 2 {
+		if index, err := strconv.ParseInt(params[1][0:2], 10, 64); err == nil {
+			details.Level = fmt.Sprintf("%d.%d", 2+index/4, index%4)
+		}
+	}
+
+	return details
+}
+
+func parseVP9(params []string) Details {
+	details := Details{Family: "VP9"}
+
+	// vp09.PP.LL.DD
+	if len(params) > 0 {
+		if profile, err := strconv.ParseInt(params[0], 10, 64); err == nil {
+			details.Profile = fmt.Sprintf("Profile %d", profile)
+		}
+	}
+	if len(params) > 1 {
+		if level, err := strconv.ParseInt(params[1], 10, 64); err == nil {
+			details.Level = decimalLevel(level, 10)
+		}
+	}
+
+	return details
+}
+
+var hevcProfiles = map[int64]string{
+	1: "Main",
+	2: "Main 10",
+	3: "Main Still Picture",
+	4: "Range Extensions",
+}
+
+func parseHEVC(params []string) Details {
+	details := Details{Family: "HEVC"}
+
+	// hvc1.[A-C]P.C.TLL.B0, the profile space prefix is optional
+	if len(params) > 0 {
+		profile := strings.TrimLeft(strings.ToUpper(params[0]), "ABC")
+		if value, err := strconv.ParseInt(profile, 10, 64); err == nil {
+			details.Profile = lookup(hevcProfiles, value)
+		}
+	}
+	if len(params) > 2 && len(params[2]) > 1 {
+		if level, err := strconv.ParseInt(params[2][1:], 10, 64); err == nil {
+			details.Level = decimalLevel(level, 30)
+		}
+	}
+
+	return details
+}
+
+var aacProfiles = map[int64]string{
+	1:  "AAC Main",
+	2:  "AAC LC",
+	3:  "AAC SSR",
+	4:  "AAC LTP",
+	5:  "HE-AAC",
+	29: "HE-AAC v2",
+	34: "MP3",
+}
+
+func parseMP4A(params []string) Details {
+	if len(params) == 0 {
+		return Details{Family: "AAC"}
+	}
+
+	// mp4a.OO[.A], where OO is the object type in hex and A the audio object type
+	switch strings.ToLower(params[0]) {
+	case "69", "6b":
+		return Details{Family: "MP3"}
+	case "a5":
+		return Details{Family: "AC-3"}
+	case "a6":
+		return Details{Family: "E-AC-3"}
+	case "40":
+		details := Details{Family: "AAC"}
+		if len(params) > 1 {
+			if objectType, err := strconv.ParseInt(params[1], 10, 64); err == nil {
+				details.Profile = lookup(aacProfiles, objectType)
+			}
+		}
+		return details
+	default:
+		return Details{Family: "AAC"}
+	}
+}
+
+func lookup(names map[int64]string, value int64) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+func decimalLevel(level int64, divisor int64) string {
+	if level%divisor == 0 {
+		return strconv.FormatInt(level/divisor, 10)
+	}
+	return strconv.FormatFloat(float64(level)/float64(divisor), 'f', 1, 64)
+}
@@ -0,0 +1,35 @@
+package codec
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		codec string
+		want  Details
+	}{
+		{"mp4a.40.2", Details{Family: "AAC", Profile: "AAC LC"}},
+		{"mp4a.40.5", Details{Family: "AAC", Profile: "HE-AAC"}},
+		{"mp4a.69", Details{Family: "MP3"}},
+		{"mp3", Details{Family: "MP3"}},
+		{"aac", Details{Family: "AAC"}},
+		{"avc1.640028", Details{Family: "H.264", Profile: "High", Level: "4"}},
+		{"avc1.4d401f", Details{Family: "H.264", Profile: "Main", Level: "3.1"}},
+		{"avc1.42001E", Details{Family: "H.264", Profile: "Baseline", Level: "3"}},
+		{"avc1", Details{Family: "H.264"}},
+		{"vp9", Details{Family: "VP9"}},
+		{"vp09.00.51.08", Details{Family: "VP9", Profile: "Profile 0", Level: "5.1"}},
+		{"av01.0.05M.08", Details{Family: "AV1", Profile: "Main", Level: "3.1"}},
+		{"opus", Details{Family: "Opus"}},
+		{"none", Details{}},
+		{"", Details{}},
+		{"unknown.1", Details{Family: "unknown"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.codec, func(t *testing.T) {
+			if got := Parse(test.codec); got != test.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", test.codec, got, test.want)
+			}
+		})
+	}
+}
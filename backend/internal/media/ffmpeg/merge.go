@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var containerArgs = map[string][]string{
+	"mp4":  {"-movflags", "frag_keyframe+empty_moov", "-f", "mp4"},
+	"webm": {"-f", "webm"},
+	"mkv":  {"-f", "matroska"},
+}
+
+// ContainerFor picks the most widely playable container able to hold both
+// codecs without re-encoding
+func ContainerFor(videoCodec string, audioCodec string) string {
+	video := strings.ToLower(videoCodec)
+	audio := strings.ToLower(audioCodec)
+
+	if hasAnyPrefix(video, "avc1", "avc3", "hvc1", "hev1", "av01") && hasAnyPrefix(audio, "mp4a", "ac-3", "ec-3", "mp3") {
+		return "mp4"
+	}
+	if hasAnyPrefix(video, "vp8", "vp08", "vp9", "vp09", "av01") && hasAnyPrefix(audio, "opus", "vorbis") {
+		return "webm"
+	}
+	return "mkv"
+}
+
+// Merge muxes the video stream of one input with the audio stream of another
+// into a single container, copying both streams as they are
+func Merge(ctx context.Context, video io.ReadCloser, audio io.ReadCloser, container string) (io.ReadCloser, error) {
+	muxArgs, ok := containerArgs[container]
+	if !ok {
+		return nil, fmt.Errorf("unsupported container: %s", container)
+	}
+
+	args := []string{
+		"-hide_banner", "-loglevel", "error",
+		"-i", inputPath(0),
+		"-i", inputPath(1),
+		"-map", "0:v:0",
+		"-map", "1:a:0",
+		"-c", "copy",
+	}
+	args = append(args, muxArgs...)
+	args = append(args, "pipe:1")
+
+	return run(ctx, []io.ReadCloser{video, audio}, args...)
+}
+
+func hasAnyPrefix(value string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeFFmpeg makes a shell script the only ffmpeg on the PATH
+func fakeFFmpeg(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("failed to write fake ffmpeg: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// closeRecorder remembers whether it was closed
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestContainerFor(t *testing.T) {
+	tests := []struct {
+		video string
+		audio string
+		want  string
+	}{
+		{"avc1.640028", "mp4a.40.2", "mp4"},
+		{"av01.0.08M.08", "mp4a.40.2", "mp4"},
+		{"hvc1.1.6.L93.B0", "ec-3", "mp4"},
+		{"vp9", "opus", "webm"},
+		{"vp09.00.40.08", "vorbis", "webm"},
+		{"av01.0.08M.08", "opus", "webm"},
+		{"AVC1.640028", "MP4A.40.2", "mp4"},
+		{"vp9", "mp4a.40.2", "mkv"},
+		{"avc1.640028", "opus", "mkv"},
+		{"", "", "mkv"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.video+"+"+test.audio, func(t *testing.T) {
+			if got := ContainerFor(test.video, test.audio); got != test.want {
+				t.Errorf("ContainerFor(%q, %q) = %q, want %q", test.video, test.audio, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// Echoes the first input followed by the second, handed over as fd 3
+	fakeFFmpeg(t, "cat\ncat /dev/fd/3\n")
+
+	video := &closeRecorder{Reader: strings.NewReader("video")}
+	audio := &closeRecorder{Reader: strings.NewReader("audio")}
+	merged, err := Merge(context.Background(), video, audio, "mp4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output, err := io.ReadAll(merged)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if string(output) != "videoaudio" {
+		t.Errorf("got output %q, want both inputs", output)
+	}
+	if err = merged.Close(); err != nil {
+		t.Errorf("unexpected error on close: %v", err)
+	}
+	if !video.closed || !audio.closed {
+		t.Error("the inputs weren't closed")
+	}
+}
+
+func TestMergeFailure(t *testing.T) {
+	fakeFFmpeg(t, "echo 'Invalid data found when processing input' >&2\nexit 1\n")
+
+	merged, err := Merge(context.Background(), io.NopCloser(strings.NewReader("video")), io.NopCloser(strings.NewReader("audio")), "webm")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, merged)
+
+	// The exit status only surfaces once the output was read
+	err = merged.Close()
+	if err == nil || !strings.Contains(err.Error(), "Invalid data found when processing input") {
+		t.Errorf("got error %v, want ffmpeg's message", err)
+	}
+	if again := merged.Close(); again != err {
+		t.Errorf("got %v closing again, want %v", again, err)
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	input := func() io.ReadCloser { return io.NopCloser(strings.NewReader("")) }
+
+	if _, err := Merge(context.Background(), input(), input(), "avi"); err == nil {
+		t.Error("got no error for an unsupported container")
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := Merge(context.Background(), input(), input(), "mp4"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("got error %v, want %v", err, ErrNotInstalled)
+	}
+	if err := Available(); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Available got %v, want %v", err, ErrNotInstalled)
+	}
+}
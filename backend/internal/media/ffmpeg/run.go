@@ -0,0 +1,119 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// inputPath returns the path ffmpeg should read the i-th input passed to run from
+func inputPath(i int) string {
+	if i == 0 {
+		return "pipe:0"
+	}
+
+	// Additional inputs are handed over as extra files, starting at fd 3
+	return fmt.Sprintf("/dev/fd/%d", 2+i)
+}
+
+func run(ctx context.Context, inputs []io.ReadCloser, args ...string) (io.ReadCloser, error) {
+	bin, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, ErrNotInstalled
+	}
+
+	cmd := exec.CommandContext(ctx, bin, args...)
+	if len(inputs) > 0 {
+		cmd.Stdin = inputs[0]
+	}
+
+	// Feed every further input through its own pipe
+	var writers []*os.File
+	for range inputs[min(1, len(inputs)):] {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			closeFiles(cmd.ExtraFiles)
+			closeFiles(writers)
+			return nil, fmt.Errorf("input pipe failed: %w", err)
+		}
+		cmd.ExtraFiles = append(cmd.ExtraFiles, reader)
+		writers = append(writers, writer)
+	}
+
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		closeFiles(cmd.ExtraFiles)
+		closeFiles(writers)
+		return nil, fmt.Errorf("stdout pipe failed: %w", err)
+	}
+	if err = cmd.Start(); err != nil {
+		closeFiles(cmd.ExtraFiles)
+		closeFiles(writers)
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	// The child holds its own copies of the read ends now
+	closeFiles(cmd.ExtraFiles)
+	for i, writer := range writers {
+		go func() {
+			_, _ = io.Copy(writer, inputs[i+1])
+			_ = writer.Close()
+		}()
+	}
+
+	return &process{
+		ReadCloser: stdout,
+		inputs:     inputs,
+		wait:       cmd.Wait,
+		stderr:     &stderr,
+	}, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+// process streams ffmpeg's stdout and reaps the process and its inputs on Close
+type process struct {
+	io.ReadCloser
+	inputs []io.ReadCloser
+	wait   func() error
+	stderr *strings.Builder
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Close reaps ffmpeg and returns why it failed, if it did. Calling it again
+// returns the same error.
+func (p *process) Close() error {
+	p.closeOnce.Do(func() {
+		p.closeErr = p.close()
+	})
+	return p.closeErr
+}
+
+func (p *process) close() error {
+	closeErr := p.ReadCloser.Close()
+	for _, input := range p.inputs {
+		_ = input.Close()
+	}
+
+	if err := p.wait(); err != nil {
+		if p.stderr.Len() > 0 {
+			return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(p.stderr.String()))
+		}
+		return fmt.Errorf("ffmpeg failed: %w", err)
+	}
+
+	return closeErr
+}
@@ -0,0 +1,37 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var subtitleMuxers = map[string]string{
+	"srt": "srt",
+	"vtt": "webvtt",
+	"ass": "ass",
+}
+
+func IsSupportedSubtitleFormat(format string) bool {
+	_, ok := subtitleMuxers[strings.ToLower(format)]
+	return ok
+}
+
+// ConvertSubtitle converts a subtitle file from one text format to another
+func ConvertSubtitle(ctx context.Context, input io.ReadCloser, from string, to string) (io.ReadCloser, error) {
+	inputMuxer, ok := subtitleMuxers[strings.ToLower(from)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported subtitle format: %s", from)
+	}
+	outputMuxer, ok := subtitleMuxers[strings.ToLower(to)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported subtitle format: %s", to)
+	}
+
+	return run(ctx, []io.ReadCloser{input},
+		"-hide_banner", "-loglevel", "error",
+		"-f", inputMuxer, "-i", inputPath(0),
+		"-f", outputMuxer, "pipe:1",
+	)
+}
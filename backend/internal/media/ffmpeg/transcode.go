@@ -0,0 +1,67 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"media-downloader/internal/media/codec"
+	"os/exec"
+	"strings"
+)
+
+var ErrNotInstalled = errors.New("ffmpeg is not installed")
+
+type audioTarget struct {
+	muxer string
+	// Codec family the target stores, audio already in it is copied as is
+	family string
+	args   []string
+}
+
+var audioTargets = map[string]audioTarget{
+	"mp3":  {muxer: "mp3", family: "MP3", args: []string{"-c:a", "libmp3lame", "-q:a", "2"}},
+	"m4a":  {muxer: "ipod", family: "AAC", args: []string{"-c:a", "aac", "-b:a", "192k"}},
+	"opus": {muxer: "opus", family: "Opus", args: []string{"-c:a", "libopus", "-b:a", "160k"}},
+	"webm": {muxer: "webm", family: "Opus", args: []string{"-c:a", "libopus", "-b:a", "160k"}},
+	"ogg":  {muxer: "ogg", family: "Vorbis", args: []string{"-c:a", "libvorbis", "-q:a", "5"}},
+	"flac": {muxer: "flac", family: "FLAC", args: []string{"-c:a", "flac"}},
+	"wav":  {muxer: "wav", args: []string{"-c:a", "pcm_s16le"}},
+}
+
+// Available returns ErrNotInstalled if ffmpeg can't be found on the PATH
+func Available() error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return ErrNotInstalled
+	}
+	return nil
+}
+
+func IsSupportedAudioFormat(format string) bool {
+	_, ok := audioTargets[strings.ToLower(format)]
+	return ok
+}
+
+// TranscodeAudio pipes input through ffmpeg, dropping any video, and streams
+// the result encoded as the given audio format. When sourceCodec already is
+// the target's codec the audio is only remuxed
+func TranscodeAudio(ctx context.Context, input io.ReadCloser, sourceCodec string, format string) (io.ReadCloser, error) {
+	target, ok := audioTargets[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported audio format: %s", format)
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-i", inputPath(0), "-vn"}
+	if target.family != "" && codec.Parse(sourceCodec).Family == target.family {
+		args = append(args, "-c:a", "copy")
+	} else {
+		args = append(args, target.args...)
+	}
+	if target.muxer == "ipod" {
+		// The moov atom can't be written at the end of a pipe
+		args = append(args, "-movflags", "frag_keyframe+empty_moov")
+	}
+	args = append(args, "-f", target.muxer, "pipe:1")
+
+	return run(ctx, []io.ReadCloser{input}, args...)
+}
@@ -0,0 +1,71 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"media-downloader/internal/media/sources"
+	"media-downloader/internal/progress"
+	"os"
+	"path/filepath"
+)
+
+// Directory DownloadToFile writes to, created on first use
+var downloadDir = filepath.Join(os.TempDir(), "media-downloader")
+
+func SetDownloadDir(dir string) {
+	downloadDir = dir
+}
+
+// DownloadToFile downloads a format into the download directory for further
+// processing and returns the path of the file, named with the format's
+// extension. Nothing is left behind on failure, removing the file once done
+// is up to the caller.
+func DownloadToFile(ctx context.Context, url string, source sources.Source, sourceIdentifier string) (path string, err error) {
+	_, format, reader, err := DownloadMedia(ctx, url, source, sourceIdentifier)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	if err = os.MkdirAll(downloadDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create download directory: %w", err)
+	}
+	file, err := os.CreateTemp(downloadDir, "download-*."+format.Extension)
+	if err != nil {
+		return "", fmt.Errorf("failed to create download file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	var input io.Reader = reader
+	if tracker, ok := progress.From(ctx); ok {
+		tracker.SetTotal(format.Size)
+		input = progress.NewReader(reader, tracker)
+	}
+
+	// Sizes are often approximate, so the limit is enforced while copying too
+	if maxDownloadBytes > 0 {
+		input = io.LimitReader(input, int64(maxDownloadBytes)+1)
+	}
+	written, err := io.Copy(file, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to write download file: %w", err)
+	}
+	if err = checkSize(uint64(written)); err != nil {
+		return "", err
+	}
+	// yt-dlp may fail after its output ended, only Close tells
+	if err = reader.Close(); err != nil {
+		return "", err
+	}
+
+	if err = file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write download file: %w", err)
+	}
+	return file.Name(), nil
+}
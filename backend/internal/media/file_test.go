@@ -0,0 +1,35 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"media-downloader/internal/media/sources"
+	"media-downloader/internal/media/ytdlp"
+	"media-downloader/internal/media/ytdlp/ytdlptest"
+	"os"
+	"testing"
+)
+
+func TestDownloadToFileRemovesFailedDownload(t *testing.T) {
+	// The format is described, but downloading it fails after part of it was written
+	fakeYtdlp(t, ytdlptest.WithArg("--output",
+		ytdlptest.Replay("partial", "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable\n", errors.New("exit status 1")),
+		ytdlptest.Replay(ytdlptest.Fixture(t, "youtube_video.json"), "", nil),
+	))
+	previous := downloadDir
+	SetDownloadDir(t.TempDir())
+	t.Cleanup(func() { SetDownloadDir(previous) })
+
+	path, err := DownloadToFile(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", sources.YouTube, "140")
+	if !errors.Is(err, ytdlp.ErrVideoUnavailable) {
+		t.Fatalf("got %q, %v, want %v", path, err, ytdlp.ErrVideoUnavailable)
+	}
+
+	entries, err := os.ReadDir(downloadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("got %d files left behind, want the partial download removed", len(entries))
+	}
+}
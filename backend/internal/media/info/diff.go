@@ -0,0 +1,43 @@
+package info
+
+import "media-downloader/internal/set"
+
+// DiffFormats compares the formats of m with those of a newer other by their
+// identifiers, reporting those only other has as added and those only m has
+// as removed. The order of the formats doesn't matter.
+func (m *Media) DiffFormats(other *Media) (added []Format, removed []Format) {
+	have := m.formatIDs()
+	want := other.formatIDs()
+
+	for _, format := range other.allFormats() {
+		if !have.Contains(format.SourceIdentifier) {
+			added = append(added, format)
+		}
+	}
+	for _, format := range m.allFormats() {
+		if !want.Contains(format.SourceIdentifier) {
+			removed = append(removed, format)
+		}
+	}
+	return added, removed
+}
+
+// allFormats returns the video formats followed by the audio formats
+func (m *Media) allFormats() []Format {
+	formats := make([]Format, 0, len(m.VideoFormats)+len(m.AudioFormats))
+	for _, format := range m.VideoFormats {
+		formats = append(formats, format.Format)
+	}
+	for _, format := range m.AudioFormats {
+		formats = append(formats, format.Format)
+	}
+	return formats
+}
+
+func (m *Media) formatIDs() set.Set[string] {
+	ids := set.New[string]()
+	for _, format := range m.allFormats() {
+		ids.Add(format.SourceIdentifier)
+	}
+	return ids
+}
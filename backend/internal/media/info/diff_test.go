@@ -0,0 +1,65 @@
+package info
+
+import (
+	"slices"
+	"testing"
+)
+
+func formatIDs(formats []Format) []string {
+	ids := []string{}
+	for _, format := range formats {
+		ids = append(ids, format.SourceIdentifier)
+	}
+	return ids
+}
+
+func TestDiffFormats(t *testing.T) {
+	cached := &Media{
+		VideoFormats: []VideoFormat{testVideo("137", 1920, 1080, 4000), testVideo("136", 1280, 720, 2000)},
+		AudioFormats: []AudioFormat{testAudio("140", "en", 128), testAudio("251", "en", 160)},
+	}
+
+	tests := []struct {
+		name    string
+		fresh   *Media
+		added   []string
+		removed []string
+	}{
+		{
+			name: "reordered but identical",
+			fresh: &Media{
+				VideoFormats: []VideoFormat{testVideo("136", 1280, 720, 2000), testVideo("137", 1920, 1080, 4000)},
+				AudioFormats: []AudioFormat{testAudio("251", "en", 160), testAudio("140", "en", 128)},
+			},
+			added:   []string{},
+			removed: []string{},
+		},
+		{
+			name: "added and removed",
+			fresh: &Media{
+				VideoFormats: []VideoFormat{testVideo("137", 1920, 1080, 4000), testVideo("248", 1920, 1080, 3000)},
+				AudioFormats: []AudioFormat{testAudio("251", "en", 160)},
+			},
+			added:   []string{"248"},
+			removed: []string{"136", "140"},
+		},
+		{
+			name:    "everything gone",
+			fresh:   &Media{},
+			added:   []string{},
+			removed: []string{"137", "136", "140", "251"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			added, removed := cached.DiffFormats(test.fresh)
+			if got := formatIDs(added); !slices.Equal(got, test.added) {
+				t.Errorf("got added %v, want %v", got, test.added)
+			}
+			if got := formatIDs(removed); !slices.Equal(got, test.removed) {
+				t.Errorf("got removed %v, want %v", got, test.removed)
+			}
+		})
+	}
+}
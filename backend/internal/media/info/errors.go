@@ -0,0 +1,10 @@
+package info
+
+import "errors"
+
+var (
+	ErrFormatNotFound       = errors.New("format not found")
+	ErrLanguageNotAvailable = errors.New("language not available")
+	ErrDRMProtected         = errors.New("format is DRM protected")
+	ErrLiveStream           = errors.New("media is a live stream")
+)
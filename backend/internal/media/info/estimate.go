@@ -0,0 +1,43 @@
+package info
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSizeUnknown means neither the size nor the bitrate of a format is known,
+// so there's nothing to estimate its size from
+var ErrSizeUnknown = errors.New("size can't be estimated")
+
+// EstimateMergedSize returns the expected size of muxing the given video and
+// audio formats together. Formats without a known size are estimated from
+// their bitrate and the media's duration, and the result is reported as
+// approximate whenever either part is.
+func (m *Media) EstimateMergedSize(videoID string, audioID string) (size uint64, approximate bool, err error) {
+	video, ok := m.videoFormat(videoID)
+	if !ok {
+		return 0, false, fmt.Errorf("%w: video format %q", ErrFormatNotFound, videoID)
+	}
+
+	audio, ok := m.audioFormat(audioID)
+	if !ok {
+		return 0, false, fmt.Errorf("%w: audio format %q", ErrFormatNotFound, audioID)
+	}
+
+	videoSize, videoApproximate := estimateSize(video.Format, video.VideoBitrate, m.Duration)
+	audioSize, audioApproximate := estimateSize(audio.Format, audio.AudioBitrate, m.Duration)
+	if videoSize == 0 || audioSize == 0 {
+		return 0, false, fmt.Errorf("%w: %q and %q", ErrSizeUnknown, videoID, audioID)
+	}
+
+	return videoSize + audioSize, videoApproximate || audioApproximate, nil
+}
+
+// estimateSize falls back to bitrate (in kbit/s) times duration for formats
+// whose size the source didn't report
+func estimateSize(format Format, bitrate float64, duration float64) (uint64, bool) {
+	if format.Size > 0 {
+		return format.Size, format.SizeApproximate
+	}
+	return uint64(bitrate * 1000 / 8 * duration), true
+}
@@ -0,0 +1,51 @@
+package info
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEstimateMergedSize(t *testing.T) {
+	media := Media{
+		Duration: 100,
+		VideoFormats: []VideoFormat{
+			{Format: Format{SourceIdentifier: "exact", Size: 5_000_000}},
+			{Format: Format{SourceIdentifier: "reported", Size: 4_000_000, SizeApproximate: true}},
+			{VideoBitrate: 800, Format: Format{SourceIdentifier: "bitrate"}},
+			{Format: Format{SourceIdentifier: "unknown"}},
+		},
+		AudioFormats: []AudioFormat{
+			{Format: Format{SourceIdentifier: "exact", Size: 1_000_000}},
+			{AudioBitrate: 128, Format: Format{SourceIdentifier: "bitrate"}},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		video           string
+		audio           string
+		wantSize        uint64
+		wantApproximate bool
+		wantErr         error
+	}{
+		{"both exact", "exact", "exact", 6_000_000, false, nil},
+		{"approximate size reported", "reported", "exact", 5_000_000, true, nil},
+		{"video from bitrate", "bitrate", "exact", 10_000_000 + 1_000_000, true, nil},
+		{"audio from bitrate", "exact", "bitrate", 5_000_000 + 1_600_000, true, nil},
+		{"no size or bitrate", "unknown", "exact", 0, false, ErrSizeUnknown},
+		{"missing video", "missing", "exact", 0, false, ErrFormatNotFound},
+		{"missing audio", "exact", "missing", 0, false, ErrFormatNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			size, approximate, err := media.EstimateMergedSize(test.video, test.audio)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if size != test.wantSize || approximate != test.wantApproximate {
+				t.Errorf("got %d (approximate %v), want %d (approximate %v)", size, approximate, test.wantSize, test.wantApproximate)
+			}
+		})
+	}
+}
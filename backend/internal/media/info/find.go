@@ -0,0 +1,51 @@
+package info
+
+import "media-downloader/internal/media/sources"
+
+// FindFormat looks a format up by its source and identifier among both the
+// video and audio formats
+func (m *Media) FindFormat(source sources.Source, id string) (*Format, bool) {
+	if video, ok := m.FindVideoFormat(source, id); ok {
+		return &video.Format, true
+	}
+	if audio, ok := m.FindAudioFormat(source, id); ok {
+		return &audio.Format, true
+	}
+	return nil, false
+}
+
+func (m *Media) FindVideoFormat(source sources.Source, id string) (*VideoFormat, bool) {
+	video, ok := m.videoFormat(id)
+	if !ok || video.Source != source {
+		return nil, false
+	}
+	return video, true
+}
+
+func (m *Media) FindAudioFormat(source sources.Source, id string) (*AudioFormat, bool) {
+	audio, ok := m.audioFormat(id)
+	if !ok || audio.Source != source {
+		return nil, false
+	}
+	return audio, true
+}
+
+// videoFormat looks a format up by its identifier alone, which is unique
+// within a media
+func (m *Media) videoFormat(id string) (*VideoFormat, bool) {
+	for i, format := range m.VideoFormats {
+		if format.SourceIdentifier == id {
+			return &m.VideoFormats[i], true
+		}
+	}
+	return nil, false
+}
+
+func (m *Media) audioFormat(id string) (*AudioFormat, bool) {
+	for i, format := range m.AudioFormats {
+		if format.SourceIdentifier == id {
+			return &m.AudioFormats[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,55 @@
+package info
+
+import (
+	"media-downloader/internal/media/sources"
+	"testing"
+)
+
+func TestFindFormat(t *testing.T) {
+	video := testVideo("137", 1920, 1080, 4000)
+	video.Source = sources.YouTube
+	audio := testAudio("140", "en", 128)
+	audio.Source = sources.YouTube
+	media := &Media{VideoFormats: []VideoFormat{video}, AudioFormats: []AudioFormat{audio}}
+
+	tests := []struct {
+		name   string
+		source sources.Source
+		id     string
+		found  bool
+	}{
+		{name: "video", source: sources.YouTube, id: "137", found: true},
+		{name: "audio", source: sources.YouTube, id: "140", found: true},
+		{name: "not found", source: sources.YouTube, id: "999"},
+		{name: "other source", source: sources.Vimeo, id: "137"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, ok := media.FindFormat(test.source, test.id)
+			if ok != test.found {
+				t.Fatalf("got found %v, want %v", ok, test.found)
+			}
+			if !ok {
+				if format != nil {
+					t.Errorf("got %+v, want nil", format)
+				}
+				return
+			}
+			if format.SourceIdentifier != test.id {
+				t.Errorf("got format %q, want %q", format.SourceIdentifier, test.id)
+			}
+		})
+	}
+
+	// The formats are returned in place rather than copied
+	if format, _ := media.FindFormat(sources.YouTube, "140"); format != &media.AudioFormats[0].Format {
+		t.Error("got a copy of the audio format")
+	}
+	if _, ok := media.FindAudioFormat(sources.YouTube, "137"); ok {
+		t.Error("FindAudioFormat found a video format")
+	}
+	if _, ok := media.FindVideoFormat(sources.YouTube, "140"); ok {
+		t.Error("FindVideoFormat found an audio format")
+	}
+}
@@ -1,50 +1,199 @@
 package info
 
 import (
+	"fmt"
+	"media-downloader/internal/media/codec"
 	"media-downloader/internal/media/sources"
+	"media-downloader/internal/set"
 	"media-downloader/internal/slice"
 	"sort"
+	"strings"
+	"time"
 )
 
 type Media struct {
+	// The source's own identifier of the media, such as a YouTube video id
+	ID           string        `json:"id,omitempty"`
 	Url          string        `json:"url"`
 	Title        string        `json:"title"`
+	CleanTitle   string        `json:"clean_title,omitempty"`
 	Duration     float64       `json:"duration"`
+	IsLive       bool          `json:"is_live"`
+	Thumbnail    string        `json:"thumbnail"`
 	VideoFormats []VideoFormat `json:"video_formats"`
 	AudioFormats []AudioFormat `json:"audio_formats"`
+	Subtitles    []Subtitle    `json:"subtitles"`
+	Chapters     []Chapter     `json:"chapters"`
+
+	// Only listed when asked for, nil otherwise
+	Storyboards []Storyboard `json:"storyboards,omitempty"`
+
+	Uploader    string `json:"uploader,omitempty"`
+	UploaderURL string `json:"uploader_url,omitempty"`
+	ViewCount   int64  `json:"view_count,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// UploadDate is the date as reported by the source (YYYYMMDD), UploadedAt
+	// the parsed form which is nil when the date is missing or malformed
+	UploadDate string     `json:"upload_date,omitempty"`
+	UploadedAt *time.Time `json:"uploaded_at,omitempty"`
+
+	// Language the caller prefers, audio and subtitles in it are sorted first
+	PreferredLanguage string `json:"preferred_language,omitempty"`
+
+	// Every language audio is offered in, even after filtering the formats
+	AvailableAudioLanguages []string `json:"audio_languages,omitempty"`
+
+	FilterStats *FilterStats `json:"filter_stats,omitempty"`
+}
+
+// Subtitle is a single subtitle track, either authored by a human or
+// automatically generated by the source
+type Subtitle struct {
+	Language    string           `json:"language"`
+	Name        string           `json:"name,omitempty"`
+	IsAutomatic bool             `json:"is_automatic"`
+	Formats     []SubtitleFormat `json:"formats"`
+}
+
+type SubtitleFormat struct {
+	Extension string `json:"extension"`
+
+	DirectURL string `json:"-"`
+}
+
+// Chapter is a titled section of the media, times are in seconds
+type Chapter struct {
+	Title     string  `json:"title"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
+
+// Storyboard is a format of preview images, each image a grid of Rows by
+// Columns thumbnails of Width by Height taken FPS times per second
+type Storyboard struct {
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Rows    int     `json:"rows"`
+	Columns int     `json:"columns"`
+	FPS     float64 `json:"fps"`
+
+	Format
+}
+
+type PlaylistEntry struct {
+	Url      string         `json:"url"`
+	Title    string         `json:"title"`
+	Duration float64        `json:"duration"`
+	Source   sources.Source `json:"source"`
+}
+
+// FilterStats counts the formats removed during extraction and cleaning, by reason
+type FilterStats struct {
+	ZeroBitrate int `json:"zero_bitrate"`
+	Duplicate   int `json:"duplicate"`
+	NonWorking  int `json:"non_working"`
+	DRM         int `json:"drm"`
+}
+
+// Stats returns the filter stats, creating them on first use
+func (m *Media) Stats() *FilterStats {
+	if m.FilterStats == nil {
+		m.FilterStats = &FilterStats{}
+	}
+	return m.FilterStats
 }
 
 type VideoFormat struct {
-	VideoCodec   string  `json:"video_codec"`
-	VideoBitrate float64 `json:"video_bitrate"`
-	VideoWidth   int     `json:"video_width"`
-	VideoHeight  int     `json:"video_height"`
-	VideoFPS     float64 `json:"video_fps"`
+	VideoCodec        string        `json:"video_codec"`
+	VideoCodecDetails codec.Details `json:"video_codec_details"`
+	VideoBitrate      float64       `json:"video_bitrate"`
+	VideoWidth        int           `json:"video_width"`
+	VideoHeight       int           `json:"video_height"`
+	VideoFPS          float64       `json:"video_fps"`
+	QualityLabel      string        `json:"quality_label"`
+	AspectRatio       float64       `json:"aspect_ratio,omitempty"`
+	IsPremium         bool          `json:"is_premium"`
+	IsWatermarked     bool          `json:"is_watermarked"`
+
+	// Progressive formats carry audio too, they're only listed for sources
+	// without separate video streams
+	HasAudio bool `json:"has_audio"`
 
 	Format
 }
 
 type AudioFormat struct {
-	AudioCodec      string  `json:"audio_codec"`
-	AudioBitrate    float64 `json:"audio_bitrate"`
-	AudioSampleRate float64 `json:"audio_sample_rate"`
+	AudioCodec        string        `json:"audio_codec"`
+	AudioCodecDetails codec.Details `json:"audio_codec_details"`
+	AudioBitrate      float64       `json:"audio_bitrate"`
+	AudioSampleRate   float64       `json:"audio_sample_rate"`
+	AudioChannels     int           `json:"audio_channels,omitempty"`
+	Language          string        `json:"language,omitempty"`
 
 	Format
 }
 
 type Format struct {
-	Extension string `json:"extension"`
-	Size      uint64 `json:"size"`
+	Extension       string `json:"extension"`
+	Size            uint64 `json:"size"`
+	SizeApproximate bool   `json:"size_approximate"`
+	HasDRM          bool   `json:"has_drm"`
+
+	// How the format is delivered, such as "https" for a progressive file or
+	// "m3u8_native" for an HLS manifest, and the container if it differs
+	// from the extension, such as "mp4_dash"
+	Protocol  string `json:"protocol,omitempty"`
+	Container string `json:"container,omitempty"`
+
+	// Where the format can be fetched directly, not exposed to clients
+	DirectURL string            `json:"-"`
+	Headers   map[string]string `json:"-"`
 
 	Source           sources.Source `json:"source"`
 	SourceIdentifier string         `json:"source_identifier"`
 }
 
-func (m *Media) CleanFormats() {
+// FileTitle is the title to use when naming downloaded files
+func (m *Media) FileTitle() string {
+	if m.CleanTitle != "" {
+		return m.CleanTitle
+	}
+	return m.Title
+}
+
+type cleanOptions struct {
+	keepDRM bool
+}
+
+type CleanOption func(*cleanOptions)
+
+// KeepDRM keeps DRM protected formats, flagged with HasDRM, instead of dropping them
+func KeepDRM() CleanOption {
+	return func(options *cleanOptions) {
+		options.keepDRM = true
+	}
+}
+
+func (m *Media) CleanFormats(opts ...CleanOption) {
+	options := cleanOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	stats := m.Stats()
+
 	// Remove unsupported video formats
 	m.VideoFormats = slice.Filter(m.VideoFormats, func(format VideoFormat) bool {
 		// Require a bitrate above zero
 		if format.VideoBitrate <= 0 {
+			stats.ZeroBitrate++
+			return false
+		}
+
+		// yt-dlp can't download DRM protected streams
+		if format.HasDRM && !options.keepDRM {
+			stats.DRM++
 			return false
 		}
 
@@ -55,6 +204,13 @@ func (m *Media) CleanFormats() {
 	m.AudioFormats = slice.Filter(m.AudioFormats, func(format AudioFormat) bool {
 		// Require a bitrate above zero
 		if format.AudioBitrate <= 0 {
+			stats.ZeroBitrate++
+			return false
+		}
+
+		// yt-dlp can't download DRM protected streams
+		if format.HasDRM && !options.keepDRM {
+			stats.DRM++
 			return false
 		}
 
@@ -62,32 +218,168 @@ func (m *Media) CleanFormats() {
 	})
 }
 
-func (m *Media) SortFormats() {
-	// Sort video formats by resolution, bitrate and file size
-	sort.Slice(m.VideoFormats, func(i, j int) bool {
-		iRes := m.VideoFormats[i].VideoWidth * m.VideoFormats[i].VideoHeight
-		jRes := m.VideoFormats[j].VideoWidth * m.VideoFormats[j].VideoHeight
-		if iRes != jRes {
-			return iRes > jRes
-		}
+// FilterByMaxHeight drops video formats taller than h, audio is left untouched
+func (m *Media) FilterByMaxHeight(h int) {
+	m.VideoFormats = slice.Filter(m.VideoFormats, func(format VideoFormat) bool {
+		return format.VideoHeight <= h
+	})
+}
 
-		iBitrate := m.VideoFormats[i].VideoBitrate
-		jBitrate := m.VideoFormats[j].VideoBitrate
-		if iBitrate != jBitrate {
-			return iBitrate > jBitrate
+// FilterByAudioLanguage keeps the audio formats in the given language, or any
+// regional variant of it, along with those without language information.
+// The languages offered before filtering stay listed in AvailableAudioLanguages.
+func (m *Media) FilterByAudioLanguage(language string) {
+	if m.AvailableAudioLanguages == nil {
+		m.AvailableAudioLanguages = m.AudioLanguages()
+	}
+
+	m.AudioFormats = slice.Filter(m.AudioFormats, func(format AudioFormat) bool {
+		return format.Language == "" || matchesLanguage(format.Language, language)
+	})
+}
+
+// PreferVideoCodec moves video formats to the front by the position of the
+// first codec prefix in order they match, such as "av01", "vp9" or "avc1".
+// Formats matching no entry go last, and the existing order is kept otherwise.
+func (m *Media) PreferVideoCodec(order []string) {
+	rank := func(format VideoFormat) int {
+		codec := strings.ToLower(format.VideoCodec)
+		for i, prefix := range order {
+			prefix = strings.ToLower(strings.TrimSpace(prefix))
+			if prefix != "" && strings.HasPrefix(codec, prefix) {
+				return i
+			}
 		}
+		return len(order)
+	}
 
-		return m.VideoFormats[i].Size > m.VideoFormats[j].Size
+	sort.SliceStable(m.VideoFormats, func(i, j int) bool {
+		return rank(m.VideoFormats[i]) < rank(m.VideoFormats[j])
 	})
+}
 
-	// Sort audio formats by bitrate and file size
+func (m *Media) SortFormats() {
+	// Sort video formats by watermark, resolution, bitrate and file size
+	sort.Slice(m.VideoFormats, func(i, j int) bool {
+		return betterVideo(m.VideoFormats[i], m.VideoFormats[j])
+	})
+
+	// Sort audio formats by preferred language, bitrate, file size and identifier
 	sort.Slice(m.AudioFormats, func(i, j int) bool {
+		iPreferred := matchesLanguage(m.AudioFormats[i].Language, m.PreferredLanguage)
+		jPreferred := matchesLanguage(m.AudioFormats[j].Language, m.PreferredLanguage)
+		if iPreferred != jPreferred {
+			return iPreferred
+		}
+
 		iBitrate := m.AudioFormats[i].AudioBitrate
 		jBitrate := m.AudioFormats[j].AudioBitrate
 		if iBitrate != jBitrate {
 			return iBitrate > jBitrate
 		}
 
-		return m.AudioFormats[i].Size > m.AudioFormats[j].Size
+		if m.AudioFormats[i].Size != m.AudioFormats[j].Size {
+			return m.AudioFormats[i].Size > m.AudioFormats[j].Size
+		}
+
+		return m.AudioFormats[i].SourceIdentifier < m.AudioFormats[j].SourceIdentifier
 	})
 }
+
+// betterVideo orders video formats by watermark, resolution, bitrate, file size
+// and finally identifier
+func betterVideo(a VideoFormat, b VideoFormat) bool {
+	if a.IsWatermarked != b.IsWatermarked {
+		return !a.IsWatermarked
+	}
+
+	aRes := a.VideoWidth * a.VideoHeight
+	bRes := b.VideoWidth * b.VideoHeight
+	if aRes != bRes {
+		return aRes > bRes
+	}
+
+	if a.VideoBitrate != b.VideoBitrate {
+		return a.VideoBitrate > b.VideoBitrate
+	}
+
+	if a.Size != b.Size {
+		return a.Size > b.Size
+	}
+
+	// Identifiers are unique, so equal formats never swap between calls
+	return a.SourceIdentifier < b.SourceIdentifier
+}
+
+// BestProgressive returns the best format carrying both video and audio that
+// isn't DRM protected, false when the media has none
+func (m *Media) BestProgressive() (*Format, bool) {
+	best := -1
+	for i, format := range m.VideoFormats {
+		if !format.HasAudio || format.HasDRM {
+			continue
+		}
+		if best < 0 || betterVideo(format, m.VideoFormats[best]) {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		return nil, false
+	}
+	return &m.VideoFormats[best].Format, true
+}
+
+func (m *Media) AudioLanguages() []string {
+	seen := set.New[string]()
+	languages := make([]string, 0)
+	for _, format := range m.AudioFormats {
+		if format.Language == "" || seen.Contains(format.Language) {
+			continue
+		}
+
+		seen.Add(format.Language)
+		languages = append(languages, format.Language)
+	}
+
+	return languages
+}
+
+// FindAudioByLanguage returns the first audio format in the given language,
+// so callers wanting the best match should sort the formats first. A bare
+// language such as "de" also matches regional variants like "de-DE".
+func (m *Media) FindAudioByLanguage(language string) (*AudioFormat, error) {
+	for i, format := range m.AudioFormats {
+		if matchesLanguage(format.Language, language) {
+			return &m.AudioFormats[i], nil
+		}
+	}
+
+	available := m.AudioLanguages()
+	if len(available) == 0 {
+		return nil, fmt.Errorf("%w: audio language %q, no language information present", ErrLanguageNotAvailable, language)
+	}
+	return nil, fmt.Errorf("%w: audio language %q, available languages: %s", ErrLanguageNotAvailable, language, strings.Join(available, ", "))
+}
+
+// FindAudioByExt returns the first audio format with the given extension, such
+// as "webm" for Opus audio, so callers wanting the best match should sort the
+// formats first
+func (m *Media) FindAudioByExt(ext string) (*AudioFormat, bool) {
+	for i, format := range m.AudioFormats {
+		if strings.EqualFold(format.Extension, ext) {
+			return &m.AudioFormats[i], true
+		}
+	}
+	return nil, false
+}
+
+func matchesLanguage(have string, want string) bool {
+	have = strings.ToLower(strings.ReplaceAll(have, "_", "-"))
+	want = strings.ToLower(strings.ReplaceAll(want, "_", "-"))
+	if have == "" || want == "" {
+		return false
+	}
+
+	return have == want || strings.HasPrefix(have, want+"-")
+}
@@ -0,0 +1,308 @@
+package info
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testAudio(id string, language string, bitrate float64) AudioFormat {
+	return AudioFormat{AudioBitrate: bitrate, Language: language, Format: Format{SourceIdentifier: id}}
+}
+
+func TestFindAudioByLanguage(t *testing.T) {
+	media := &Media{AudioFormats: []AudioFormat{
+		testAudio("en", "en", 128),
+		testAudio("de-DE", "de-DE", 128),
+		testAudio("pt_BR", "pt_BR", 128),
+		testAudio("unknown", "", 160),
+	}}
+
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"en", "en"},
+		{"EN", "en"},
+		{"de", "de-DE"},
+		{"de-de", "de-DE"},
+		{"pt-BR", "pt_BR"},
+	}
+	for _, test := range tests {
+		t.Run(test.language, func(t *testing.T) {
+			audio, err := media.FindAudioByLanguage(test.language)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if audio.SourceIdentifier != test.want {
+				t.Errorf("got %q, want %q", audio.SourceIdentifier, test.want)
+			}
+		})
+	}
+}
+
+func TestFindAudioByLanguageNotFound(t *testing.T) {
+	media := &Media{AudioFormats: []AudioFormat{
+		testAudio("en", "en", 128),
+		testAudio("de", "de-DE", 128),
+		testAudio("en-low", "en", 64),
+	}}
+
+	_, err := media.FindAudioByLanguage("es")
+	if !errors.Is(err, ErrLanguageNotAvailable) {
+		t.Fatalf("got error %v, want %v", err, ErrLanguageNotAvailable)
+	}
+	if !strings.HasSuffix(err.Error(), "available languages: en, de-DE") {
+		t.Errorf("error %q doesn't list the available languages once each", err)
+	}
+
+	// A bare language doesn't match another one merely sharing its prefix
+	if _, err = media.FindAudioByLanguage("e"); !errors.Is(err, ErrLanguageNotAvailable) {
+		t.Errorf("got error %v, want %v", err, ErrLanguageNotAvailable)
+	}
+}
+
+func TestFindAudioByLanguageWithoutLanguages(t *testing.T) {
+	media := &Media{AudioFormats: []AudioFormat{testAudio("140", "", 128)}}
+
+	_, err := media.FindAudioByLanguage("de")
+	if !errors.Is(err, ErrLanguageNotAvailable) {
+		t.Fatalf("got error %v, want %v", err, ErrLanguageNotAvailable)
+	}
+	if !strings.Contains(err.Error(), "no language information present") {
+		t.Errorf("error %q doesn't explain that no languages are known", err)
+	}
+}
+
+func TestCleanFormatsStats(t *testing.T) {
+	media := &Media{
+		VideoFormats: []VideoFormat{
+			{VideoBitrate: 4000, Format: Format{SourceIdentifier: "137"}},
+			{VideoBitrate: 0, Format: Format{SourceIdentifier: "136"}},
+		},
+		AudioFormats: []AudioFormat{
+			testAudio("140", "en", 128),
+			testAudio("139", "en", 0),
+			testAudio("249", "en", 0),
+		},
+	}
+
+	media.CleanFormats()
+
+	if got := media.Stats().ZeroBitrate; got != 3 {
+		t.Errorf("got %d formats without a bitrate, want 3", got)
+	}
+	if len(media.VideoFormats) != 1 || len(media.AudioFormats) != 1 {
+		t.Errorf("got %d video and %d audio formats, want 1 of each", len(media.VideoFormats), len(media.AudioFormats))
+	}
+}
+
+func testVideo(id string, width int, height int, bitrate float64) VideoFormat {
+	return VideoFormat{VideoWidth: width, VideoHeight: height, VideoBitrate: bitrate, Format: Format{SourceIdentifier: id}}
+}
+
+func videoIdentifiers(formats []VideoFormat) []string {
+	identifiers := []string{}
+	for _, format := range formats {
+		identifiers = append(identifiers, format.SourceIdentifier)
+	}
+	return identifiers
+}
+
+func TestFilterByMaxHeight(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxHeight int
+		want      []string
+	}{
+		{name: "at the cap", maxHeight: 1080, want: []string{"1080", "720", "unknown"}},
+		{name: "between heights", maxHeight: 900, want: []string{"720", "unknown"}},
+		{name: "above every height", maxHeight: 4320, want: []string{"2160", "1080", "720", "unknown"}},
+		{name: "below every height", maxHeight: 144, want: []string{"unknown"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			media := &Media{
+				VideoFormats: []VideoFormat{
+					testVideo("2160", 3840, 2160, 12000),
+					testVideo("1080", 1920, 1080, 4000),
+					testVideo("720", 1280, 720, 2000),
+					testVideo("unknown", 0, 0, 0),
+				},
+				AudioFormats: []AudioFormat{testAudio("140", "en", 128), testAudio("251", "en", 160)},
+			}
+
+			media.FilterByMaxHeight(test.maxHeight)
+
+			if got := videoIdentifiers(media.VideoFormats); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if len(media.AudioFormats) != 2 {
+				t.Errorf("got %d audio formats, want the 2 untouched", len(media.AudioFormats))
+			}
+		})
+	}
+}
+
+func TestCleanFormatsDRM(t *testing.T) {
+	newMedia := func() *Media {
+		return &Media{
+			VideoFormats: []VideoFormat{
+				{VideoBitrate: 4000, Format: Format{SourceIdentifier: "137"}},
+				{VideoBitrate: 4000, Format: Format{SourceIdentifier: "drm-video", HasDRM: true}},
+			},
+			AudioFormats: []AudioFormat{
+				{AudioBitrate: 128, Format: Format{SourceIdentifier: "140"}},
+				{AudioBitrate: 128, Format: Format{SourceIdentifier: "drm-audio", HasDRM: true}},
+			},
+		}
+	}
+
+	media := newMedia()
+	media.CleanFormats()
+	if len(media.VideoFormats) != 1 || len(media.AudioFormats) != 1 || media.FilterStats.DRM != 2 {
+		t.Errorf("got %d video and %d audio formats with %d dropped for DRM, want 1, 1 and 2", len(media.VideoFormats), len(media.AudioFormats), media.FilterStats.DRM)
+	}
+
+	media = newMedia()
+	media.CleanFormats(KeepDRM())
+	if len(media.VideoFormats) != 2 || len(media.AudioFormats) != 2 || media.FilterStats.DRM != 0 {
+		t.Errorf("got %d video and %d audio formats with %d dropped for DRM, want all kept", len(media.VideoFormats), len(media.AudioFormats), media.FilterStats.DRM)
+	}
+}
+
+func TestBestProgressive(t *testing.T) {
+	progressive := func(id string, width int, height int, drm bool) VideoFormat {
+		format := testVideo(id, width, height, 1000)
+		format.HasAudio = true
+		format.HasDRM = drm
+		return format
+	}
+
+	tests := []struct {
+		name    string
+		formats []VideoFormat
+		want    string
+	}{
+		{
+			name: "highest resolution with audio",
+			formats: []VideoFormat{
+				testVideo("1080-video-only", 1920, 1080, 4000),
+				progressive("360", 640, 360, false),
+				progressive("720", 1280, 720, false),
+			},
+			want: "720",
+		},
+		{
+			name: "drm skipped",
+			formats: []VideoFormat{
+				progressive("720-drm", 1280, 720, true),
+				progressive("360", 640, 360, false),
+			},
+			want: "360",
+		},
+		{
+			name:    "none",
+			formats: []VideoFormat{testVideo("1080-video-only", 1920, 1080, 4000)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			media := &Media{VideoFormats: test.formats}
+
+			format, ok := media.BestProgressive()
+			if test.want == "" {
+				if ok {
+					t.Errorf("got %q, want none", format.SourceIdentifier)
+				}
+				return
+			}
+			if !ok || format.SourceIdentifier != test.want {
+				t.Errorf("got %v, %v, want %q", format, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestFindAudioByExt(t *testing.T) {
+	media := &Media{AudioFormats: []AudioFormat{
+		{AudioBitrate: 160, Format: Format{Extension: "webm", SourceIdentifier: "251"}},
+		{AudioBitrate: 128, Format: Format{Extension: "m4a", SourceIdentifier: "140"}},
+		{AudioBitrate: 48, Format: Format{Extension: "m4a", SourceIdentifier: "139"}},
+	}}
+
+	if format, ok := media.FindAudioByExt("M4A"); !ok || format.SourceIdentifier != "140" {
+		t.Errorf("got %v, %v, want the first m4a format", format, ok)
+	}
+	if format, ok := media.FindAudioByExt("mp3"); ok {
+		t.Errorf("got %v, want no mp3 format", format)
+	}
+}
+
+func TestFilterByAudioLanguage(t *testing.T) {
+	media := &Media{AudioFormats: []AudioFormat{
+		testAudio("en", "en", 128),
+		testAudio("en-US", "en-US", 128),
+		testAudio("de", "de", 128),
+		testAudio("unknown", "", 160),
+	}}
+
+	media.FilterByAudioLanguage("en")
+
+	var got []string
+	for _, format := range media.AudioFormats {
+		got = append(got, format.SourceIdentifier)
+	}
+	if want := []string{"en", "en-US", "unknown"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !slices.Contains(media.AvailableAudioLanguages, "de") {
+		t.Errorf("got available languages %v, want de still listed", media.AvailableAudioLanguages)
+	}
+}
+
+func TestSortFormatsIsDeterministic(t *testing.T) {
+	// Every format ties with another on everything but its identifier
+	newMedia := func() *Media {
+		return &Media{
+			VideoFormats: []VideoFormat{
+				testVideo("b", 1920, 1080, 4000),
+				testVideo("d", 1280, 720, 2000),
+				testVideo("a", 1920, 1080, 4000),
+				testVideo("c", 1280, 720, 2000),
+			},
+			AudioFormats: []AudioFormat{
+				testAudio("z", "en", 128),
+				testAudio("x", "en", 128),
+				testAudio("y", "en", 128),
+			},
+		}
+	}
+
+	first := newMedia()
+	first.SortFormats()
+	second := newMedia()
+	slices.Reverse(second.VideoFormats)
+	slices.Reverse(second.AudioFormats)
+	second.SortFormats()
+	second.SortFormats()
+
+	wantVideo := []string{"a", "b", "c", "d"}
+	wantAudio := []string{"x", "y", "z"}
+	for _, media := range []*Media{first, second} {
+		if got := videoIdentifiers(media.VideoFormats); !slices.Equal(got, wantVideo) {
+			t.Errorf("got video order %v, want %v", got, wantVideo)
+		}
+
+		var gotAudio []string
+		for _, format := range media.AudioFormats {
+			gotAudio = append(gotAudio, format.SourceIdentifier)
+		}
+		if !slices.Equal(gotAudio, wantAudio) {
+			t.Errorf("got audio order %v, want %v", gotAudio, wantAudio)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package info
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortSubtitles orders tracks by language, preferred language first, with
+// manual tracks before automatic ones
+func (m *Media) SortSubtitles() {
+	sort.SliceStable(m.Subtitles, func(i, j int) bool {
+		iPreferred := matchesLanguage(m.Subtitles[i].Language, m.PreferredLanguage)
+		jPreferred := matchesLanguage(m.Subtitles[j].Language, m.PreferredLanguage)
+		if iPreferred != jPreferred {
+			return iPreferred
+		}
+
+		if m.Subtitles[i].Language != m.Subtitles[j].Language {
+			return m.Subtitles[i].Language < m.Subtitles[j].Language
+		}
+		return !m.Subtitles[i].IsAutomatic && m.Subtitles[j].IsAutomatic
+	})
+}
+
+// FindSubtitle returns the track for the given language, preferring a manual
+// track and only falling back to an automatic one when allowAutomatic is set.
+// An empty extension accepts any format, otherwise the track must offer it.
+func (m *Media) FindSubtitle(language string, extension string, allowAutomatic bool) (*Subtitle, *SubtitleFormat, error) {
+	var automatic *Subtitle
+	var automaticFormat *SubtitleFormat
+	for i := range m.Subtitles {
+		subtitle := &m.Subtitles[i]
+		if !matchesLanguage(subtitle.Language, language) {
+			continue
+		}
+
+		format, ok := subtitle.findFormat(extension)
+		if !ok {
+			continue
+		}
+
+		if !subtitle.IsAutomatic {
+			return subtitle, format, nil
+		}
+		if automatic == nil {
+			automatic, automaticFormat = subtitle, format
+		}
+	}
+
+	if automatic != nil && allowAutomatic {
+		return automatic, automaticFormat, nil
+	}
+	if automatic != nil {
+		return nil, nil, fmt.Errorf("%w: only automatic captions are available for language %q", ErrLanguageNotAvailable, language)
+	}
+	return nil, nil, fmt.Errorf("%w: no subtitles available for language %q", ErrLanguageNotAvailable, language)
+}
+
+func (s *Subtitle) findFormat(extension string) (*SubtitleFormat, bool) {
+	if len(s.Formats) == 0 {
+		return nil, false
+	}
+	if extension == "" {
+		return &s.Formats[0], true
+	}
+
+	for i, format := range s.Formats {
+		if strings.EqualFold(format.Extension, extension) {
+			return &s.Formats[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,93 @@
+package info
+
+import (
+	"errors"
+	"testing"
+)
+
+func subtitle(language string, automatic bool, extensions ...string) Subtitle {
+	formats := make([]SubtitleFormat, 0, len(extensions))
+	for _, extension := range extensions {
+		formats = append(formats, SubtitleFormat{Extension: extension, DirectURL: language + "." + extension})
+	}
+	return Subtitle{Language: language, IsAutomatic: automatic, Formats: formats}
+}
+
+func TestFindSubtitlePrefersManual(t *testing.T) {
+	// The automatic track is listed first so order alone doesn't pick manual
+	media := &Media{Subtitles: []Subtitle{
+		subtitle("en", true, "vtt", "srv3"),
+		subtitle("en", false, "vtt"),
+		subtitle("de", true, "vtt"),
+	}}
+
+	tests := []struct {
+		name           string
+		language       string
+		extension      string
+		allowAutomatic bool
+		wantAutomatic  bool
+		wantErr        bool
+	}{
+		{"manual over automatic", "en", "vtt", false, false, false},
+		{"manual even when automatic is allowed", "en", "vtt", true, false, false},
+		{"automatic only in a format manual lacks", "en", "srv3", true, true, false},
+		{"automatic not allowed", "en", "srv3", false, false, true},
+		{"automatic fallback", "de", "", true, true, false},
+		{"only automatic available", "de", "", false, false, true},
+		{"missing language", "fr", "", true, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subtitle, format, err := media.FindSubtitle(test.language, test.extension, test.allowAutomatic)
+			if test.wantErr {
+				if !errors.Is(err, ErrLanguageNotAvailable) {
+					t.Fatalf("got error %v, want %v", err, ErrLanguageNotAvailable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if subtitle.IsAutomatic != test.wantAutomatic {
+				t.Errorf("got automatic %v, want %v", subtitle.IsAutomatic, test.wantAutomatic)
+			}
+			if test.extension != "" && format.Extension != test.extension {
+				t.Errorf("got extension %q, want %q", format.Extension, test.extension)
+			}
+		})
+	}
+}
+
+func TestSortSubtitles(t *testing.T) {
+	media := &Media{
+		PreferredLanguage: "es",
+		Subtitles: []Subtitle{
+			subtitle("en", true, "vtt"),
+			subtitle("en", false, "vtt"),
+			subtitle("es", true, "vtt"),
+			subtitle("de", false, "vtt"),
+			subtitle("es", false, "vtt"),
+		},
+	}
+	media.SortSubtitles()
+
+	want := []struct {
+		language  string
+		automatic bool
+	}{
+		{"es", false},
+		{"es", true},
+		{"de", false},
+		{"en", false},
+		{"en", true},
+	}
+	for i, subtitle := range media.Subtitles {
+		if subtitle.Language != want[i].language || subtitle.IsAutomatic != want[i].automatic {
+			t.Errorf("position %d: got %s (automatic %v), want %s (automatic %v)",
+				i, subtitle.Language, subtitle.IsAutomatic, want[i].language, want[i].automatic)
+		}
+	}
+}
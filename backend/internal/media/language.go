@@ -0,0 +1,19 @@
+package media
+
+import "context"
+
+type languageKey struct{}
+
+// WithLanguage attaches a preferred language to a request, which audio
+// selection, subtitle selection and sorting consult where they can
+func WithLanguage(ctx context.Context, language string) context.Context {
+	if language == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, languageKey{}, language)
+}
+
+func LanguageFrom(ctx context.Context) (string, bool) {
+	language, ok := ctx.Value(languageKey{}).(string)
+	return language, ok && language != ""
+}
@@ -0,0 +1,124 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/media/ytdlp"
+	"media-downloader/internal/media/ytdlp/ytdlptest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeYtdlp runs process in place of yt-dlp for the rest of the test
+func fakeYtdlp(t *testing.T, process ytdlptest.Process) {
+	t.Helper()
+	ytdlp.SetRunner(ytdlptest.New(process))
+
+	// Every test fakes different output for the same URL
+	ytdlp.SetCache(0, 0, 0)
+	t.Cleanup(func() {
+		ytdlp.SetRunner(nil)
+		ytdlp.SetCache(5*time.Minute, 256, 0)
+	})
+}
+
+// dubbedJSON describes media dubbed in English and Spanish with subtitles
+// served from baseURL. English audio has the higher bitrate, so it wins
+// without a preference.
+func dubbedJSON(t *testing.T, baseURL string) string {
+	t.Helper()
+	fixture := ytdlptest.Fixture(t, "youtube_dubbed.json")
+	return strings.ReplaceAll(fixture, "https://www.youtube.com/api/timedtext", baseURL+"/api/timedtext")
+}
+
+func TestLanguagePreference(t *testing.T) {
+	fakeYtdlp(t, ytdlptest.Replay(dubbedJSON(t, "https://www.youtube.com"), "", nil))
+
+	tests := []struct {
+		name         string
+		language     string
+		wantAudio    string
+		wantSubtitle string
+	}{
+		{name: "dubbed audio deduped, spanish subtitles", language: "es", wantAudio: "en-US", wantSubtitle: "es-419"},
+		{name: "only automatic subtitles", language: "de", wantAudio: "en-US", wantSubtitle: "de"},
+		{name: "language available for neither", language: "fr", wantAudio: "en-US", wantSubtitle: "de"},
+		{name: "no preference", language: "", wantAudio: "en-US", wantSubtitle: "de"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := WithLanguage(context.Background(), test.language)
+
+			mediaInfo, err := FetchMedia(ctx, "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			mediaInfo.CleanFormats()
+			mediaInfo.SortFormats()
+
+			if got := mediaInfo.AudioFormats[0].Language; got != test.wantAudio {
+				t.Errorf("got audio in %q first, want %q", got, test.wantAudio)
+			}
+			if got := mediaInfo.Subtitles[0].Language; got != test.wantSubtitle {
+				t.Errorf("got subtitles in %q first, want %q", got, test.wantSubtitle)
+			}
+		})
+	}
+}
+
+func TestDownloadSubtitle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body tells which track was fetched
+		_, _ = io.WriteString(w, r.URL.Query().Get("lang")+"."+r.URL.Query().Get("fmt"))
+	}))
+	defer server.Close()
+	fakeYtdlp(t, ytdlptest.Replay(dubbedJSON(t, server.URL), "", nil))
+
+	tests := []struct {
+		name           string
+		preferred      string
+		language       string
+		allowAutomatic bool
+		wantSubtitle   string
+		wantErr        error
+	}{
+		{name: "preferred language", preferred: "es", wantSubtitle: "es-419.vtt"},
+		{name: "explicit language wins", preferred: "es", language: "en", wantSubtitle: "en.vtt"},
+		{name: "automatic captions not allowed", language: "de", wantErr: info.ErrLanguageNotAvailable},
+		{name: "automatic captions allowed", language: "de", allowAutomatic: true, wantSubtitle: "de.vtt"},
+		{name: "language not available", language: "fr", allowAutomatic: true, wantErr: info.ErrLanguageNotAvailable},
+		{name: "no language", wantErr: ErrInvalidRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := WithLanguage(context.Background(), test.preferred)
+
+			_, _, extension, reader, err := DownloadSubtitle(ctx, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", test.language, "vtt", test.allowAutomatic)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("got error %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer reader.Close()
+
+			body, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("failed to read subtitle: %v", err)
+			}
+			if string(body) != test.wantSubtitle || extension != "vtt" {
+				t.Errorf("got %q as %s, want %q as vtt", body, extension, test.wantSubtitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package media
+
+import "media-downloader/internal/media/ytdlp"
+
+// FetchOption adjusts how the media info is extracted, without any the
+// defaults of the extractor apply
+type FetchOption = ytdlp.Option
+
+// WithProxy routes the extraction through an HTTP or SOCKS proxy
+func WithProxy(url string) FetchOption {
+	return ytdlp.WithProxy(url)
+}
+
+// WithGeoBypassCountry pretends the request comes from the given two letter
+// country code to get around geo restrictions
+func WithGeoBypassCountry(country string) FetchOption {
+	return ytdlp.WithGeoBypassCountry(country)
+}
+
+// WithFormatSort changes the order formats are ranked in by the extractor
+func WithFormatSort(sort string) FetchOption {
+	return ytdlp.WithFormatSort(sort)
+}
+
+// WithStoryboards lists the storyboard formats used for scrubbing previews
+func WithStoryboards() FetchOption {
+	return ytdlp.WithStoryboards()
+}
+
+// WithoutFormatCheck trades accuracy for speed, formats aren't probed and may
+// not all work
+func WithoutFormatCheck() FetchOption {
+	return ytdlp.WithoutFormatCheck()
+}
@@ -0,0 +1,71 @@
+package media
+
+import (
+	"context"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/slice"
+	"net/http"
+	"time"
+)
+
+const (
+	probeConcurrency = 8
+	probeTimeout     = 10 * time.Second
+)
+
+var probeClient = &http.Client{}
+
+// ProbeExactSizes replaces approximate format sizes with the Content-Length
+// reported by the format's server, keeping the approximation on any failure
+func ProbeExactSizes(ctx context.Context, m *info.Media) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	var formats []*info.Format
+	for i := range m.VideoFormats {
+		formats = append(formats, &m.VideoFormats[i].Format)
+	}
+	for i := range m.AudioFormats {
+		formats = append(formats, &m.AudioFormats[i].Format)
+	}
+
+	sizes := slice.MapConcurrent(formats, probeConcurrency, func(format *info.Format) int64 {
+		return probeSize(ctx, format)
+	})
+
+	for i, size := range sizes {
+		if size <= 0 {
+			continue
+		}
+
+		formats[i].Size = uint64(size)
+		formats[i].SizeApproximate = false
+	}
+}
+
+func probeSize(ctx context.Context, format *info.Format) int64 {
+	// Only progressive files answer with their full size, for HLS and DASH
+	// it would be the size of the manifest or a single fragment
+	if format.DirectURL == "" || (format.Protocol != "http" && format.Protocol != "https") {
+		return 0
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, format.DirectURL, nil)
+	if err != nil {
+		return 0
+	}
+	for key, value := range format.Headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := probeClient.Do(request)
+	if err != nil {
+		return 0
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return 0
+	}
+	return response.ContentLength
+}
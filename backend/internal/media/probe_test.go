@@ -0,0 +1,77 @@
+package media
+
+import (
+	"context"
+	"media-downloader/internal/media/info"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeExactSizes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("got %s request, want HEAD", r.Method)
+		}
+
+		switch r.URL.Path {
+		case "/video":
+			w.Header().Set("Content-Length", "1048576")
+		case "/audio", "/playlist.m3u8", "/manifest.mpd":
+			w.Header().Set("Content-Length", "65536")
+		case "/signed":
+			// Some sources only answer requests carrying their headers
+			if r.Header.Get("Referer") != "https://example.com/" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Content-Length", "4096")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	approximate := func(url string, headers map[string]string) info.Format {
+		return info.Format{Size: 1000, SizeApproximate: true, Protocol: "https", DirectURL: url, Headers: headers}
+	}
+	streamed := func(url string, protocol string) info.Format {
+		return info.Format{Size: 1000, SizeApproximate: true, Protocol: protocol, DirectURL: url}
+	}
+	media := &info.Media{
+		VideoFormats: []info.VideoFormat{
+			{Format: approximate(server.URL+"/video", nil)},
+			{Format: approximate(server.URL+"/missing", nil)},
+			{Format: approximate("", nil)},
+			{Format: streamed(server.URL+"/playlist.m3u8", "m3u8_native")},
+			{Format: streamed(server.URL+"/manifest.mpd", "http_dash_segments")},
+		},
+		AudioFormats: []info.AudioFormat{
+			{Format: approximate(server.URL+"/audio", nil)},
+			{Format: approximate(server.URL+"/signed", map[string]string{"Referer": "https://example.com/"})},
+		},
+	}
+
+	ProbeExactSizes(context.Background(), media)
+
+	tests := []struct {
+		name        string
+		format      info.Format
+		size        uint64
+		approximate bool
+	}{
+		{"video", media.VideoFormats[0].Format, 1048576, false},
+		{"failed probe", media.VideoFormats[1].Format, 1000, true},
+		{"no direct url", media.VideoFormats[2].Format, 1000, true},
+		{"hls", media.VideoFormats[3].Format, 1000, true},
+		{"dash", media.VideoFormats[4].Format, 1000, true},
+		{"audio", media.AudioFormats[0].Format, 65536, false},
+		{"with headers", media.AudioFormats[1].Format, 4096, false},
+	}
+	for _, test := range tests {
+		if test.format.Size != test.size || test.format.SizeApproximate != test.approximate {
+			t.Errorf("%s: got size %d (approximate %v), want %d (approximate %v)",
+				test.name, test.format.Size, test.format.SizeApproximate, test.size, test.approximate)
+		}
+	}
+}
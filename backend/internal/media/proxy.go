@@ -0,0 +1,29 @@
+package media
+
+import (
+	"fmt"
+	"media-downloader/internal/media/ytdlp"
+	"net/http"
+	"net/url"
+)
+
+// SetProxy routes yt-dlp and the direct probe and subtitle fetches through the
+// given proxy, as direct URLs are often bound to the address that resolved
+// them. An empty url keeps the proxy environment variables in effect.
+func SetProxy(proxy string) error {
+	ytdlp.SetProxy(proxy)
+	if proxy == "" {
+		return nil
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return fmt.Errorf("invalid proxy url: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(u)
+	probeClient.Transport = transport
+	subtitleClient.Transport = transport
+	return nil
+}
@@ -2,29 +2,433 @@ package media
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"media-downloader/internal/media/ffmpeg"
 	"media-downloader/internal/media/info"
 	"media-downloader/internal/media/sources"
+	"media-downloader/internal/media/title"
 	"media-downloader/internal/media/ytdlp"
+	"strings"
 )
 
-func FetchMedia(ctx context.Context, url string) (*info.Media, error) {
-	source := sources.IdentifySource(url)
+var (
+	ErrUnsupportedSource = errors.New("unsupported source")
+	ErrInvalidRequest    = errors.New("invalid request")
+	ErrTooLarge          = errors.New("download exceeds the maximum size")
+)
+
+var titleCleaner = title.Default()
+
+// Largest download in bytes that will be started, zero means unlimited
+var maxDownloadBytes uint64
+
+func SetMaxDownloadBytes(n uint64) {
+	maxDownloadBytes = n
+}
+
+// checkSize rejects formats known to be larger than the configured maximum,
+// formats of unknown size are left to be enforced while streaming
+func checkSize(size uint64) error {
+	if maxDownloadBytes > 0 && size > maxDownloadBytes {
+		return fmt.Errorf("%w: %d bytes, the limit is %d", ErrTooLarge, size, maxDownloadBytes)
+	}
+	return nil
+}
+
+func SetTitleCleaner(cleaner *title.Cleaner) {
+	titleCleaner = cleaner
+}
+
+// FetchMedia fetches the media's metadata and formats, the options adjust
+// how yt-dlp extracts them
+func FetchMedia(ctx context.Context, url string, opts ...FetchOption) (*info.Media, error) {
+	source, err := identifySource(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var mediaInfo *info.Media
+	switch source {
+	case sources.YouTube, sources.Vimeo, sources.SoundCloud, sources.TikTok, sources.Instagram, sources.Reddit:
+		mediaInfo, err = ytdlp.GetAvailableFormats(ctx, url, source, opts...)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mediaInfo.CleanTitle = titleCleaner.Clean(mediaInfo.Title)
+
+	if err = checkClip(ctx, mediaInfo); err != nil {
+		return nil, err
+	}
+
+	if language, ok := LanguageFrom(ctx); ok {
+		mediaInfo.PreferredLanguage = language
+		mediaInfo.SortSubtitles()
+	}
+
+	return mediaInfo, nil
+}
+
+// FetchMetadata fetches the media's title, duration and other metadata
+// without its formats, much faster than FetchMedia
+func FetchMetadata(ctx context.Context, url string, opts ...FetchOption) (*info.Media, error) {
+	source, err := identifySource(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var mediaInfo *info.Media
+	switch source {
+	case sources.YouTube, sources.Vimeo, sources.SoundCloud, sources.TikTok, sources.Instagram, sources.Reddit:
+		mediaInfo, err = ytdlp.GetMetadata(ctx, url, opts...)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mediaInfo.CleanTitle = titleCleaner.Clean(mediaInfo.Title)
+	return mediaInfo, nil
+}
+
+// FetchRawInfo returns the extractor's unprocessed output for url, meant for
+// debugging only as it may contain internal fields
+func FetchRawInfo(ctx context.Context, url string) (json.RawMessage, error) {
+	source, err := identifySource(url)
+	if err != nil {
+		return nil, err
+	}
 
 	switch source {
-	case sources.YouTube:
-		return ytdlp.GetAvailableFormats(url)
+	case sources.YouTube, sources.Vimeo, sources.SoundCloud, sources.TikTok, sources.Instagram, sources.Reddit:
+		return ytdlp.RawJSON(ctx, url)
 	default:
-		return nil, fmt.Errorf("unsupported source: %s", source)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, source)
+	}
+}
+
+// identifySource rejects URLs yt-dlp shouldn't be handed before identifying them
+func identifySource(url string) (sources.Source, error) {
+	if err := sources.ValidateURL(url); err != nil {
+		return sources.Unknown, err
+	}
+	return sources.IdentifySource(url), nil
+}
+
+func StreamPlaylist(ctx context.Context, url string, yield func(info.PlaylistEntry) error) error {
+	source, err := identifySource(url)
+	if err != nil {
+		return err
+	}
+
+	switch source {
+	case sources.YouTube, sources.Vimeo, sources.SoundCloud, sources.TikTok, sources.Instagram, sources.Reddit:
+		return ytdlp.StreamPlaylist(ctx, url, source, yield)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedSource, source)
 	}
 }
 
 func DownloadMedia(ctx context.Context, url string, source sources.Source, sourceIdentifier string) (*info.Media, *info.Format, io.ReadCloser, error) {
+	// Reddit's video streams are silent on their own, so the audio is merged in
+	if source == sources.Reddit {
+		if audioID, ok := bestAudioFor(ctx, url, source, sourceIdentifier); ok {
+			return DownloadMerged(ctx, url, sourceIdentifier, audioID)
+		}
+	}
+
+	mediaInfo, format, err := ResolveMedia(ctx, url, source, sourceIdentifier)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	reader, err := streamFormat(ctx, url, source, sourceIdentifier)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return mediaInfo, format, reader, nil
+}
+
+// ResolveMedia looks up a format and checks it can be downloaded, without
+// starting the download
+func ResolveMedia(ctx context.Context, url string, source sources.Source, sourceIdentifier string) (*info.Media, *info.Format, error) {
+	if identified, err := identifySource(url); err != nil {
+		return nil, nil, err
+	} else if identified != source {
+		return nil, nil, fmt.Errorf("%w: url does not belong to source %s", ErrInvalidRequest, source)
+	}
+
+	mediaInfo, err := FetchMedia(ctx, url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	format, ok := mediaInfo.FindFormat(source, sourceIdentifier)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q", info.ErrFormatNotFound, sourceIdentifier)
+	}
+
+	if format.HasDRM {
+		return nil, nil, fmt.Errorf("%w: %q", info.ErrDRMProtected, sourceIdentifier)
+	}
+
+	// Premium formats are only served to logged in members
+	video, isVideo := mediaInfo.FindVideoFormat(source, sourceIdentifier)
+	if isVideo && video.IsPremium && !ytdlp.HasCookies(ctx, url) {
+		return nil, nil, ytdlp.ErrPremiumRequiresCookies
+	}
+
+	// A clip is roughly its share of the whole
+	if clip, ok := ClipFrom(ctx); ok && mediaInfo.Duration > 0 {
+		clipped := *format
+		clipped.Size = uint64(float64(format.Size) * (clip.End - clip.Start) / mediaInfo.Duration)
+		clipped.SizeApproximate = true
+		format = &clipped
+	}
+
+	if err = checkSize(format.Size); err != nil {
+		return nil, nil, err
+	}
+
+	return mediaInfo, format, nil
+}
+
+// bestAudioFor returns the best audio format to merge with the video-only
+// format videoID, false when videoID isn't one or there is no audio
+func bestAudioFor(ctx context.Context, url string, source sources.Source, videoID string) (string, bool) {
+	mediaInfo, err := FetchMedia(ctx, url)
+	if err != nil {
+		return "", false
+	}
+
+	video, ok := mediaInfo.FindVideoFormat(source, videoID)
+	isVideoOnly := ok && !video.HasAudio
+
+	mediaInfo.CleanFormats()
+	mediaInfo.SortFormats()
+	if !isVideoOnly || len(mediaInfo.AudioFormats) == 0 {
+		return "", false
+	}
+	return mediaInfo.AudioFormats[0].SourceIdentifier, true
+}
+
+// streamFormat starts downloading a format already known to exist
+func streamFormat(ctx context.Context, url string, source sources.Source, sourceIdentifier string) (io.ReadCloser, error) {
 	switch source {
-	//case sources.YouTube:
-	//return youtube.DownloadMedia(ctx, url, sourceIdentifier)
+	case sources.YouTube, sources.Vimeo, sources.SoundCloud, sources.TikTok, sources.Instagram, sources.Reddit:
+		var opts []ytdlp.DownloadOption
+		if clip, ok := ClipFrom(ctx); ok {
+			opts = append(opts, ytdlp.Section(clip.Start, clip.End))
+		}
+		return ytdlp.Download(ctx, url, sourceIdentifier, opts...)
 	default:
-		return nil, nil, nil, fmt.Errorf("unsupported source: %s", source)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, source)
+	}
+}
+
+// ExtractAudioTrack downloads the best audio-only format in the given language
+// and transcodes it to audioFormat unless it is already in that format. Without
+// a language the request's preferred language is used if available, falling
+// back to the best audio format overall.
+func ExtractAudioTrack(ctx context.Context, url string, language string, audioFormat string) (*info.Media, *info.Format, io.ReadCloser, error) {
+	mediaInfo, err := FetchMedia(ctx, url)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	mediaInfo.CleanFormats()
+	mediaInfo.SortFormats()
+
+	var audio *info.AudioFormat
+	if language != "" {
+		if audio, err = mediaInfo.FindAudioByLanguage(language); err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		if len(mediaInfo.AudioFormats) == 0 {
+			return nil, nil, nil, fmt.Errorf("%w: no audio formats available", info.ErrFormatNotFound)
+		}
+		audio = &mediaInfo.AudioFormats[0]
+	}
+
+	// A track already in the requested format needs no transcoding
+	if native, ok := mediaInfo.FindAudioByExt(audioFormat); ok && native.Language == audio.Language {
+		audio = native
+	}
+
+	format, reader, err := streamAudio(ctx, url, audio, audioFormat)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return mediaInfo, format, reader, nil
+}
+
+// ExtractAudio streams the audio-only format with the given identifier,
+// transcoded to targetExt unless it's already in that format
+func ExtractAudio(ctx context.Context, url string, audioID string, targetExt string) (*info.Media, *info.Format, io.ReadCloser, error) {
+	mediaInfo, err := FetchMedia(ctx, url)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	audio, ok := mediaInfo.FindAudioFormat(sources.IdentifySource(url), audioID)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("%w: audio format %q", info.ErrFormatNotFound, audioID)
+	}
+
+	if audio.HasDRM {
+		return nil, nil, nil, info.ErrDRMProtected
+	}
+
+	format, reader, err := streamAudio(ctx, url, audio, targetExt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return mediaInfo, format, reader, nil
+}
+
+// streamAudio streams an audio format, passing it through ffmpeg when its
+// extension differs from targetExt. Targets that are neither the format's own
+// extension nor one ffmpeg can produce are rejected
+func streamAudio(ctx context.Context, url string, audio *info.AudioFormat, targetExt string) (*info.Format, io.ReadCloser, error) {
+	native := strings.EqualFold(audio.Extension, targetExt)
+	if !native && !ffmpeg.IsSupportedAudioFormat(targetExt) {
+		return nil, nil, fmt.Errorf("%w: audio format %s is neither offered by the source nor available through transcoding", ErrInvalidRequest, targetExt)
+	}
+
+	// Transcoding changes the size, but not by enough to matter here
+	if err := checkSize(audio.Size); err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := streamFormat(ctx, url, audio.Source, audio.SourceIdentifier)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	format := audio.Format
+	if native {
+		return &format, reader, nil
+	}
+
+	transcoded, err := ffmpeg.TranscodeAudio(ctx, reader, audio.AudioCodec, targetExt)
+	if err != nil {
+		_ = reader.Close()
+		return nil, nil, err
+	}
+
+	// The size of the transcoded output isn't known up front
+	format.Extension = strings.ToLower(targetExt)
+	format.Size = 0
+
+	return &format, transcoded, nil
+}
+
+// DownloadBest downloads the best progressive format, or when the media offers
+// none and allowMerge is set, merges the best video-only and audio-only formats
+func DownloadBest(ctx context.Context, url string, allowMerge bool) (*info.Media, *info.Format, io.ReadCloser, error) {
+	mediaInfo, err := FetchMedia(ctx, url)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if format, ok := mediaInfo.BestProgressive(); ok {
+		return DownloadMedia(ctx, url, format.Source, format.SourceIdentifier)
+	}
+	if !allowMerge {
+		return nil, nil, nil, fmt.Errorf("%w: no progressive format available", info.ErrFormatNotFound)
+	}
+
+	mediaInfo.CleanFormats()
+	mediaInfo.SortFormats()
+
+	var videoID string
+	for _, format := range mediaInfo.VideoFormats {
+		if format.IsPremium && !ytdlp.HasCookies(ctx, url) {
+			continue
+		}
+		videoID = format.SourceIdentifier
+		break
+	}
+	if videoID == "" || len(mediaInfo.AudioFormats) == 0 {
+		return nil, nil, nil, fmt.Errorf("%w: no downloadable video and audio formats", info.ErrFormatNotFound)
+	}
+
+	return DownloadMerged(ctx, url, videoID, mediaInfo.AudioFormats[0].SourceIdentifier)
+}
+
+// DownloadMerged downloads a video-only and an audio-only format and muxes
+// them with ffmpeg into a single file, streamed as it's produced
+func DownloadMerged(ctx context.Context, url string, videoID string, audioID string) (*info.Media, *info.Format, io.ReadCloser, error) {
+	if err := ffmpeg.Available(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	mediaInfo, err := FetchMedia(ctx, url)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// FetchMedia already validated the URL
+	source := sources.IdentifySource(url)
+	video, ok := mediaInfo.FindVideoFormat(source, videoID)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("%w: video format %q", info.ErrFormatNotFound, videoID)
+	}
+
+	audio, ok := mediaInfo.FindAudioFormat(source, audioID)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("%w: audio format %q", info.ErrFormatNotFound, audioID)
+	}
+
+	if video.HasDRM || audio.HasDRM {
+		return nil, nil, nil, info.ErrDRMProtected
+	}
+
+	if video.IsPremium && !ytdlp.HasCookies(ctx, url) {
+		return nil, nil, nil, ytdlp.ErrPremiumRequiresCookies
+	}
+
+	if size, _, err := mediaInfo.EstimateMergedSize(videoID, audioID); err == nil {
+		if err = checkSize(size); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	videoReader, err := streamFormat(ctx, url, video.Source, video.SourceIdentifier)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	audioReader, err := streamFormat(ctx, url, audio.Source, audio.SourceIdentifier)
+	if err != nil {
+		_ = videoReader.Close()
+		return nil, nil, nil, err
 	}
+
+	container := ffmpeg.ContainerFor(video.VideoCodec, audio.AudioCodec)
+	merged, err := ffmpeg.Merge(ctx, videoReader, audioReader, container)
+	if err != nil {
+		_ = videoReader.Close()
+		_ = audioReader.Close()
+		return nil, nil, nil, err
+	}
+
+	// Muxing overhead is small enough for the sum to be a useful estimate
+	format := video.Format
+	format.Extension = container
+	format.Size, _, _ = mediaInfo.EstimateMergedSize(videoID, audioID)
+	format.SizeApproximate = true
+	format.SourceIdentifier = videoID + "+" + audioID
+
+	return mediaInfo, &format, merged, nil
 }
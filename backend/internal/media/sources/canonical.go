@@ -0,0 +1,47 @@
+package sources
+
+import (
+	URL "net/url"
+	"regexp"
+	"strings"
+)
+
+var youtubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// Path prefixes followed by the video id on YouTube's own domains
+var youtubeIDPrefixes = []string{"/shorts/", "/embed/", "/live/", "/v/"}
+
+// CanonicalizeURL reduces the many URLs of a single YouTube video, short
+// links, music.youtube.com, embeds and watch URLs carrying playlist or time
+// parameters, to https://www.youtube.com/watch?v=ID. Any other URL, including
+// YouTube URLs not pointing at a single video, is returned unchanged.
+func CanonicalizeURL(url string) string {
+	if IdentifySource(url) != YouTube {
+		return url
+	}
+
+	urlObj, err := URL.Parse(url)
+	if err != nil {
+		return url
+	}
+
+	var id string
+	hostname := strings.TrimSuffix(strings.ToLower(urlObj.Hostname()), ".")
+	if matchesDomain("youtu.be", hostname) {
+		id = strings.Trim(urlObj.Path, "/")
+	} else if urlObj.Path == "/watch" {
+		id = urlObj.Query().Get("v")
+	} else {
+		for _, prefix := range youtubeIDPrefixes {
+			if rest, ok := strings.CutPrefix(urlObj.Path, prefix); ok {
+				id = strings.TrimSuffix(rest, "/")
+				break
+			}
+		}
+	}
+
+	if !youtubeIDPattern.MatchString(id) {
+		return url
+	}
+	return "https://www.youtube.com/watch?v=" + id
+}
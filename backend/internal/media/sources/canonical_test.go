@@ -0,0 +1,41 @@
+package sources
+
+import "testing"
+
+func TestCanonicalizeURL(t *testing.T) {
+	const canonical = "https://www.youtube.com/watch?v=dQw4w9WgXcQ"
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", canonical},
+		{"http://youtube.com/watch?v=dQw4w9WgXcQ", canonical},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ", canonical},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PLxyz&index=3", canonical},
+		{"https://www.youtube.com/watch?list=PLxyz&v=dQw4w9WgXcQ", canonical},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s", canonical},
+		{"https://youtu.be/dQw4w9WgXcQ", canonical},
+		{"https://youtu.be/dQw4w9WgXcQ?t=42&si=abc", canonical},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ&feature=share", canonical},
+		{"https://www.youtube.com/shorts/dQw4w9WgXcQ", canonical},
+		{"https://www.youtube.com/embed/dQw4w9WgXcQ?start=10", canonical},
+		{"https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", canonical},
+		{"https://www.youtube.com/live/dQw4w9WgXcQ/", canonical},
+
+		// Not a single video, or not YouTube, left as is
+		{"https://www.youtube.com/playlist?list=PLxyz", "https://www.youtube.com/playlist?list=PLxyz"},
+		{"https://www.youtube.com/@channel", "https://www.youtube.com/@channel"},
+		{"https://www.youtube.com/watch?v=tooshort", "https://www.youtube.com/watch?v=tooshort"},
+		{"https://youtube.com.evil.tld/watch?v=dQw4w9WgXcQ", "https://youtube.com.evil.tld/watch?v=dQw4w9WgXcQ"},
+		{"https://vimeo.com/123456?t=10", "https://vimeo.com/123456?t=10"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			if got := CanonicalizeURL(test.url); got != test.want {
+				t.Errorf("CanonicalizeURL(%q) = %q, want %q", test.url, got, test.want)
+			}
+		})
+	}
+}
@@ -10,18 +10,92 @@ type Source int
 const (
 	YouTube Source = iota
 	Unknown
+
+	// Sources added later go here so the values clients know stay the same
+	Vimeo
+	SoundCloud
+	TikTok
+	Instagram
+	Reddit
 )
 
 func (s Source) String() string {
 	switch s {
 	case YouTube:
 		return "YouTube"
+	case Vimeo:
+		return "Vimeo"
+	case SoundCloud:
+		return "SoundCloud"
+	case TikTok:
+		return "TikTok"
+	case Instagram:
+		return "Instagram"
+	case Reddit:
+		return "Reddit"
 	default:
 		return "Unknown"
 	}
 }
 
-const youtubeHostnames = "youtube.com;youtu.be;www.youtube.com;www.youtu.be;youtube-nocookie.com;www.youtube-nocookie.com"
+// FromString is the inverse of String, ignoring case. Unknown names, including
+// "Unknown" itself, aren't parsed
+func FromString(s string) (Source, bool) {
+	for _, entry := range registry {
+		if strings.EqualFold(entry.source.String(), s) {
+			return entry.source, true
+		}
+	}
+	return Unknown, false
+}
+
+// YouTube serves from many subdomains, such as music.youtube.com, so any
+// subdomain of its domains matches. The hostnames of other sources only match
+// exactly, leaving their unknown subdomains Unknown.
+const youtubeHostnames = "youtube.com;youtu.be;youtube-nocookie.com"
+const vimeoHostnames = "vimeo.com;www.vimeo.com;player.vimeo.com"
+const soundcloudHostnames = "soundcloud.com;www.soundcloud.com;m.soundcloud.com"
+const tiktokHostnames = "tiktok.com;www.tiktok.com;m.tiktok.com;vm.tiktok.com;vt.tiktok.com"
+const instagramHostnames = "instagram.com;www.instagram.com"
+const redditHostnames = "reddit.com;www.reddit.com;old.reddit.com;new.reddit.com;redd.it;v.redd.it"
+
+type registration struct {
+	source    Source
+	hostnames string
+	audioOnly bool
+
+	// Whether subdomains of the hostnames match too
+	subdomains bool
+}
+
+// Every supported source, in the order they are matched and listed
+var registry = []registration{
+	{source: YouTube, hostnames: youtubeHostnames, subdomains: true},
+	{source: Vimeo, hostnames: vimeoHostnames},
+	{source: SoundCloud, hostnames: soundcloudHostnames, audioOnly: true},
+	{source: TikTok, hostnames: tiktokHostnames},
+	{source: Instagram, hostnames: instagramHostnames},
+	{source: Reddit, hostnames: redditHostnames},
+}
+
+func All() []Source {
+	all := make([]Source, 0, len(registry))
+	for _, entry := range registry {
+		all = append(all, entry.source)
+	}
+	return all
+}
+
+// IsAudioOnly reports whether a source never serves video, as opposed to a
+// particular media item that happens to lack video formats
+func IsAudioOnly(s Source) bool {
+	for _, entry := range registry {
+		if entry.source == s {
+			return entry.audioOnly
+		}
+	}
+	return false
+}
 
 func IdentifySource(url string) Source {
 	urlObj, err := URL.Parse(url)
@@ -29,19 +103,34 @@ func IdentifySource(url string) Source {
 		return Unknown
 	}
 
-	hostname := strings.ToLower(urlObj.Hostname())
+	// A fully qualified hostname may end in a dot
+	hostname := strings.TrimSuffix(strings.ToLower(urlObj.Hostname()), ".")
 
-	// YouTube
-	if contains(youtubeHostnames, hostname) {
-		return YouTube
+	for _, entry := range registry {
+		if entry.subdomains && matchesDomain(entry.hostnames, hostname) {
+			return entry.source
+		}
+		if !entry.subdomains && contains(entry.hostnames, hostname) {
+			return entry.source
+		}
 	}
 
 	return Unknown
 }
 
+// matchesDomain reports whether hostname is one of the domains or a subdomain
+// of one, requiring a dot before the domain so notyoutube.com doesn't match
+func matchesDomain(domains string, hostname string) bool {
+	for _, domain := range strings.Split(strings.ToLower(domains), ";") {
+		if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
 func contains(haystack string, needle string) bool {
-	heystackValues := strings.Split(strings.ToLower(haystack), ";")
-	for _, value := range heystackValues {
+	for _, value := range strings.Split(strings.ToLower(haystack), ";") {
 		if value == needle {
 			return true
 		}
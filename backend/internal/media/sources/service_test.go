@@ -0,0 +1,114 @@
+package sources
+
+import "testing"
+
+func TestIsAudioOnly(t *testing.T) {
+	tests := []struct {
+		source Source
+		want   bool
+	}{
+		{SoundCloud, true},
+		{YouTube, false},
+		{Vimeo, false},
+		{TikTok, false},
+		{Instagram, false},
+		{Reddit, false},
+		{Unknown, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source.String(), func(t *testing.T) {
+			if got := IsAudioOnly(test.source); got != test.want {
+				t.Errorf("IsAudioOnly(%s) = %v, want %v", test.source, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIdentifySource(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Source
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", YouTube},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ", YouTube},
+		{"https://m.youtube.com/watch?v=dQw4w9WgXcQ", YouTube},
+		{"https://youtu.be/dQw4w9WgXcQ", YouTube},
+		{"https://WWW.YouTube.com./watch?v=dQw4w9WgXcQ", YouTube},
+		{"https://youtube.com.evil.tld/watch?v=dQw4w9WgXcQ", Unknown},
+		{"https://notyoutube.com/watch?v=dQw4w9WgXcQ", Unknown},
+		{"https://vimeo.com/123456", Vimeo},
+		{"https://player.vimeo.com/video/123456", Vimeo},
+		{"https://evil.vimeo.com/123456", Unknown},
+		{"https://vimeo.com.evil.tld/123456", Unknown},
+		{"https://soundcloud.com/artist/track", SoundCloud},
+		{"https://api.soundcloud.com/tracks/123", Unknown},
+		{"https://www.tiktok.com/@user/video/123", TikTok},
+		{"https://vm.tiktok.com/abc", TikTok},
+		{"https://www.instagram.com/reel/CxYz123/", Instagram},
+		{"https://www.reddit.com/r/videos/comments/abc123/title/", Reddit},
+		{"https://v.redd.it/abc123", Reddit},
+		{"https://example.com/video", Unknown},
+		{"not a url", Unknown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			if got := IdentifySource(test.url); got != test.want {
+				t.Errorf("IdentifySource(%q) = %s, want %s", test.url, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFromString(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   Source
+		wantOk bool
+	}{
+		{"YouTube", YouTube, true},
+		{"youtube", YouTube, true},
+		{"VIMEO", Vimeo, true},
+		{"soundcloud", SoundCloud, true},
+		{"TikTok", TikTok, true},
+		{"instagram", Instagram, true},
+		{"Reddit", Reddit, true},
+		{"Unknown", Unknown, false},
+		{"dailymotion", Unknown, false},
+		{"", Unknown, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := FromString(test.name)
+			if got != test.want || ok != test.wantOk {
+				t.Errorf("FromString(%q) = %s, %v, want %s, %v", test.name, got, ok, test.want, test.wantOk)
+			}
+		})
+	}
+}
+
+func TestSourceValues(t *testing.T) {
+	// Clients store these numbers, they must never change
+	tests := []struct {
+		source Source
+		want   int
+	}{
+		{YouTube, 0},
+		{Unknown, 1},
+		{Vimeo, 2},
+		{SoundCloud, 3},
+		{TikTok, 4},
+		{Instagram, 5},
+		{Reddit, 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source.String(), func(t *testing.T) {
+			if got := int(test.source); got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
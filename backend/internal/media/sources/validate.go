@@ -0,0 +1,57 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	URL "net/url"
+	"strings"
+)
+
+var ErrInvalidURL = errors.New("invalid url")
+
+// Whether URLs may point at loopback, private and other internal addresses
+var allowPrivateHosts = false
+
+func SetAllowPrivateHosts(allow bool) {
+	allowPrivateHosts = allow
+}
+
+// ValidateURL only accepts absolute http(s) URLs and, unless allowed, rejects
+// hosts that are internal addresses so yt-dlp can't be used to reach them.
+// Hostnames are checked as written, not resolved.
+func ValidateURL(url string) error {
+	urlObj, err := URL.Parse(strings.TrimSpace(url))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	scheme := strings.ToLower(urlObj.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return fmt.Errorf("%w: only http and https urls are supported", ErrInvalidURL)
+	}
+
+	hostname := strings.TrimSuffix(strings.ToLower(urlObj.Hostname()), ".")
+	if hostname == "" {
+		return fmt.Errorf("%w: missing host", ErrInvalidURL)
+	}
+
+	if !allowPrivateHosts && isInternalHost(hostname) {
+		return fmt.Errorf("%w: internal hosts are not allowed", ErrInvalidURL)
+	}
+
+	return nil
+}
+
+func isInternalHost(hostname string) bool {
+	if hostname == "localhost" || strings.HasSuffix(hostname, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(hostname)
+	if ip == nil {
+		return false
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
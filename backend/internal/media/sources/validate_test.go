@@ -0,0 +1,57 @@
+package sources
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", true},
+		{"http://vimeo.com/123456", true},
+		{"  https://soundcloud.com/artist/track  ", true},
+		{"https://93.184.216.34/video", true},
+		{"file:///etc/passwd", false},
+		{"ftp://example.com/video", false},
+		{"www.youtube.com/watch?v=dQw4w9WgXcQ", false},
+		{"https:///watch", false},
+		{"http://localhost:8080/admin", false},
+		{"http://api.localhost/", false},
+		{"http://LOCALHOST./", false},
+		{"http://127.0.0.1/", false},
+		{"http://10.0.0.1/", false},
+		{"http://192.168.1.1/", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://0.0.0.0/", false},
+		{"http://[::1]/", false},
+		{"http://[fd00::1]/", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			err := ValidateURL(test.url)
+			if test.valid && err != nil {
+				t.Errorf("got error %v, want none", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidURL) {
+				t.Errorf("got error %v, want %v", err, ErrInvalidURL)
+			}
+		})
+	}
+}
+
+func TestValidateURLAllowPrivateHosts(t *testing.T) {
+	SetAllowPrivateHosts(true)
+	t.Cleanup(func() { SetAllowPrivateHosts(false) })
+
+	if err := ValidateURL("http://127.0.0.1:8080/video.mp4"); err != nil {
+		t.Errorf("got error %v for an allowed private host", err)
+	}
+	// The scheme is checked regardless
+	if err := ValidateURL("file:///etc/passwd"); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("got error %v, want %v", err, ErrInvalidURL)
+	}
+}
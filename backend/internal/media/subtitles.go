@@ -0,0 +1,84 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"media-downloader/internal/media/ffmpeg"
+	"media-downloader/internal/media/info"
+	"net/http"
+	"strings"
+)
+
+// Subtitles are tiny, but the source may still be slow to answer
+var subtitleClient = &http.Client{}
+
+// DownloadSubtitle fetches the subtitle track in the given language, or the
+// request's preferred language when empty. Manual tracks are preferred and
+// automatic captions are only used when allowAutomatic is set. A track not
+// natively offered in the requested format is converted from WebVTT. The
+// returned extension is that of the streamed file.
+func DownloadSubtitle(ctx context.Context, url string, language string, extension string, allowAutomatic bool) (*info.Media, *info.Subtitle, string, io.ReadCloser, error) {
+	if language == "" {
+		language, _ = LanguageFrom(ctx)
+	}
+	if language == "" {
+		return nil, nil, "", nil, fmt.Errorf("%w: no subtitle language requested", ErrInvalidRequest)
+	}
+	extension = strings.ToLower(extension)
+
+	mediaInfo, err := FetchMedia(ctx, url)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+
+	// Use the requested format as is if the source offers it
+	subtitle, format, err := mediaInfo.FindSubtitle(language, extension, allowAutomatic)
+	if err == nil {
+		reader, err := fetchSubtitle(ctx, format.DirectURL)
+		if err != nil {
+			return nil, nil, "", nil, err
+		}
+		return mediaInfo, subtitle, format.Extension, reader, nil
+	}
+	if extension == "" || extension == "vtt" || !ffmpeg.IsSupportedSubtitleFormat(extension) {
+		return nil, nil, "", nil, err
+	}
+
+	// Otherwise convert from WebVTT, which every source yt-dlp knows of offers
+	subtitle, format, err = mediaInfo.FindSubtitle(language, "vtt", allowAutomatic)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+
+	reader, err := fetchSubtitle(ctx, format.DirectURL)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+
+	converted, err := ffmpeg.ConvertSubtitle(ctx, reader, "vtt", extension)
+	if err != nil {
+		_ = reader.Close()
+		return nil, nil, "", nil, err
+	}
+
+	return mediaInfo, subtitle, extension, converted, nil
+}
+
+func fetchSubtitle(ctx context.Context, url string) (io.ReadCloser, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subtitle url: %w", err)
+	}
+
+	response, err := subtitleClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch subtitle: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("failed to fetch subtitle: %s", response.Status)
+	}
+
+	return response.Body, nil
+}
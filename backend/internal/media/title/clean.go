@@ -0,0 +1,89 @@
+package title
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// DefaultPatterns only strip noise that is almost never part of the real title
+var DefaultPatterns = []string{
+	`(?i)[\(\[]\s*official\s+(music\s+|lyrics?\s+)?(video|audio|visuali[sz]er)\s*[\)\]]`,
+	`(?i)[\(\[]\s*(lyrics?|lyric\s+video)\s*[\)\]]`,
+	`(?i)[\(\[]\s*(4k|8k|uhd|hd|hq|full\s+hd|\d{3,4}p)(\s+remaster(ed)?)?\s*[\)\]]`,
+	`[\x{1F000}-\x{1FAFF}\x{2600}-\x{27BF}\x{FE0F}\x{200D}]`,
+}
+
+var (
+	whitespace = regexp.MustCompile(`\s+`)
+	dangling   = regexp.MustCompile(`(^[\s\-|:]+|[\s\-|:]+$)`)
+)
+
+type Cleaner struct {
+	patterns []*regexp.Regexp
+}
+
+func NewCleaner(patterns []string) (*Cleaner, error) {
+	cleaner := &Cleaner{}
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title pattern %q: %w", pattern, err)
+		}
+		cleaner.patterns = append(cleaner.patterns, compiled)
+	}
+
+	return cleaner, nil
+}
+
+func Default() *Cleaner {
+	cleaner, err := NewCleaner(DefaultPatterns)
+	if err != nil {
+		panic(err)
+	}
+	return cleaner
+}
+
+// LoadPatterns reads one regular expression per line, skipping blank lines and
+// lines starting with #
+func LoadPatterns(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open title patterns: %w", err)
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read title patterns: %w", err)
+	}
+
+	return patterns, nil
+}
+
+func (c *Cleaner) Clean(title string) string {
+	cleaned := title
+	for _, pattern := range c.patterns {
+		cleaned = pattern.ReplaceAllString(cleaned, " ")
+	}
+
+	// Tidy up whatever separators the removed parts left behind
+	cleaned = whitespace.ReplaceAllString(cleaned, " ")
+	cleaned = dangling.ReplaceAllString(cleaned, "")
+
+	// Never clean a title away entirely
+	if cleaned == "" {
+		return strings.TrimSpace(title)
+	}
+	return cleaned
+}
@@ -0,0 +1,72 @@
+package title
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDefaultClean(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Artist - Song (Official Video)", "Artist - Song"},
+		{"Artist - Song [Official Music Video]", "Artist - Song"},
+		{"Artist - Song (Lyrics)", "Artist - Song"},
+		{"Artist - Song (Official Audio) [4K]", "Artist - Song"},
+		{"Artist - Song [1080p HD]", "Artist - Song [1080p HD]"},
+		{"Artist - Song [2160p]", "Artist - Song"},
+		{"🔥 Artist - Song 🔥", "Artist - Song"},
+		{"Artist - Song | (Official Visualizer)", "Artist - Song"},
+		{"Artist  -   Song", "Artist - Song"},
+		{"The Official Video Of Everything", "The Official Video Of Everything"},
+		{"(Official Video)", "(Official Video)"},
+		{"🔥🔥🔥", "🔥🔥🔥"},
+	}
+
+	cleaner := Default()
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			if got := cleaner.Clean(test.title); got != test.want {
+				t.Errorf("Clean(%q) = %q, want %q", test.title, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCustomPatterns(t *testing.T) {
+	cleaner, err := NewCleaner([]string{`(?i)\s*-\s*topic$`, `#\w+`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := cleaner.Clean("Channel - Topic"); got != "Channel" {
+		t.Errorf("got %q, want %q", got, "Channel")
+	}
+	if got := cleaner.Clean("Great song #music #viral"); got != "Great song" {
+		t.Errorf("got %q, want %q", got, "Great song")
+	}
+
+	if _, err = NewCleaner([]string{"("}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestLoadPatterns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "patterns.txt")
+	content := "# Strip hashtags\n#\\w+\n\n  \\[free download\\]  \n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns, err := LoadPatterns(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{`\[free download\]`}
+	if !slices.Equal(patterns, want) {
+		t.Errorf("got %q, want %q", patterns, want)
+	}
+}
@@ -0,0 +1,106 @@
+package ytdlp
+
+import (
+	"context"
+	"media-downloader/internal/cache"
+	"media-downloader/internal/media/sources"
+	"time"
+)
+
+// How often expired extractions are dropped from memory
+const cacheCleanInterval = time.Minute
+
+// Set apart extractions whose formats weren't checked
+const metadataKeyPrefix = "metadata\n"
+
+// resultCache holds recent extractions so repeated requests for the same
+// media don't spawn yt-dlp again, nil when caching is disabled. The cached
+// info is shared and must not be modified.
+var resultCache = newResultCache(5*time.Minute, 256)
+
+// SetCache replaces the cache of extracted media info, a ttl of zero disables
+// it. With a positive slidingCeiling every hit keeps an entry for another ttl,
+// up to slidingCeiling after it was extracted.
+func SetCache(ttl time.Duration, maxEntries int, slidingCeiling time.Duration) {
+	StopCache()
+	resultCache = newResultCache(ttl, maxEntries)
+	if resultCache != nil {
+		resultCache.EnableSliding(slidingCeiling)
+	}
+}
+
+// StopCache stops the background cleaning of the cache
+func StopCache() {
+	if resultCache != nil {
+		resultCache.Stop()
+	}
+}
+
+func newResultCache(ttl time.Duration, maxEntries int) *cache.TTLCache[string, *MediaInfo] {
+	if ttl <= 0 {
+		return nil
+	}
+
+	c := cache.NewTTLCache[string, *MediaInfo](ttl, maxEntries)
+	c.StartCleaner(cacheCleanInterval)
+	return c
+}
+
+// CacheStats returns the statistics of the current cache, all zero when
+// caching is disabled
+func CacheStats() cache.Stats {
+	if resultCache == nil {
+		return cache.Stats{}
+	}
+	return resultCache.Stats()
+}
+
+// cacheKey identifies the extraction of url for ctx with the given options.
+// Logged in requests may see different formats than anonymous ones so the
+// cookies are part of it, and URLs of the same video share an entry.
+func cacheKey(ctx context.Context, url string, options fetchOptions) (string, error) {
+	cookies, err := cookiesFor(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	return cookies + "\n" + options.cacheKey() + "\n" + sources.CanonicalizeURL(url), nil
+}
+
+// CachedFor returns how much longer the extracted formats of url stay
+// cached, zero if they aren't
+func CachedFor(ctx context.Context, url string, opts ...Option) time.Duration {
+	if resultCache == nil {
+		return 0
+	}
+
+	options := newFetchOptions(opts)
+	key, err := cacheKey(ctx, url, options)
+	if err != nil {
+		return 0
+	}
+	ttl, ok := resultCache.TTL(key)
+	if !ok && options.skipFormatCheck {
+		ttl, _ = resultCache.TTL(metadataKeyPrefix + key)
+	}
+	return ttl
+}
+
+// cacheGet returns the cached info of the first key that has any
+func cacheGet(keys ...string) (*MediaInfo, bool) {
+	if resultCache == nil {
+		return nil, false
+	}
+
+	for _, key := range keys {
+		if info, ok := resultCache.Get(key); ok {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+func cacheSet(key string, info *MediaInfo) {
+	if resultCache != nil {
+		resultCache.Set(key, info)
+	}
+}
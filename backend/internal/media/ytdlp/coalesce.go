@@ -0,0 +1,68 @@
+package ytdlp
+
+import (
+	"context"
+	"sync"
+)
+
+// inflightCall is a metadata extraction shared by every request for the same
+// URL while it runs
+type inflightCall struct {
+	done   chan struct{}
+	info   *MediaInfo
+	err    error
+	cancel context.CancelFunc
+
+	// Requests still waiting for the result, guarded by inflightMutex
+	waiters int
+}
+
+var (
+	inflightMutex sync.Mutex
+	inflight      = make(map[string]*inflightCall)
+)
+
+// coalesce runs fetch once for concurrent requests of the same key, handing
+// the result or error to all of them. The extraction isn't tied to the
+// request that started it, it's only cancelled once every waiter gave up
+func coalesce(ctx context.Context, key string, fetch func(ctx context.Context) (*MediaInfo, error)) (*MediaInfo, error) {
+	inflightMutex.Lock()
+	call, ok := inflight[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &inflightCall{done: make(chan struct{}), cancel: cancel}
+		inflight[key] = call
+
+		go func() {
+			call.info, call.err = fetch(callCtx)
+
+			inflightMutex.Lock()
+			if inflight[key] == call {
+				delete(inflight, key)
+			}
+			inflightMutex.Unlock()
+
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	inflightMutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.info, call.err
+	case <-ctx.Done():
+		inflightMutex.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Nobody is left, later requests start a fresh extraction
+			call.cancel()
+			if inflight[key] == call {
+				delete(inflight, key)
+			}
+		}
+		inflightMutex.Unlock()
+		return nil, ctx.Err()
+	}
+}
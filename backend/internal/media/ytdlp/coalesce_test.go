@@ -0,0 +1,118 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForWaiters blocks until the extraction of key has n waiters
+func waitForWaiters(t *testing.T, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		inflightMutex.Lock()
+		call, ok := inflight[key]
+		waiters := 0
+		if ok {
+			waiters = call.waiters
+		}
+		inflightMutex.Unlock()
+
+		if waiters == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("extraction of %q never had %d waiters", key, n)
+}
+
+func TestCoalesceOutlivesCancelledInitiator(t *testing.T) {
+	const key = "coalesce-initiator"
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fetch := func(ctx context.Context) (*MediaInfo, error) {
+		calls.Add(1)
+		select {
+		case <-release:
+			return &MediaInfo{ID: "shared"}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	initiatorCtx, cancelInitiator := context.WithCancel(context.Background())
+	initiatorErr := make(chan error, 1)
+	go func() {
+		_, err := coalesce(initiatorCtx, key, fetch)
+		initiatorErr <- err
+	}()
+	waitForWaiters(t, key, 1)
+
+	type result struct {
+		info *MediaInfo
+		err  error
+	}
+	results := make(chan result, 2)
+	for range 2 {
+		go func() {
+			info, err := coalesce(context.Background(), key, fetch)
+			results <- result{info, err}
+		}()
+	}
+	waitForWaiters(t, key, 3)
+
+	cancelInitiator()
+	if err := <-initiatorErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("initiator got error %v, want %v", err, context.Canceled)
+	}
+
+	close(release)
+	for range 2 {
+		result := <-results
+		if result.err != nil {
+			t.Fatalf("waiter got error %v", result.err)
+		}
+		if result.info.ID != "shared" {
+			t.Errorf("waiter got %+v, want the shared result", result.info)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("fetched %d times, want once", calls.Load())
+	}
+}
+
+func TestCoalesceCancelsOnceEveryWaiterLeft(t *testing.T) {
+	const key = "coalesce-abandoned"
+	cancelled := make(chan struct{})
+	fetch := func(ctx context.Context) (*MediaInfo, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := coalesce(ctx, key, fetch)
+			errs <- err
+		}()
+	}
+	waitForWaiters(t, key, 2)
+
+	cancel()
+	for range 2 {
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the extraction kept running after every waiter left")
+	}
+}
@@ -0,0 +1,153 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"media-downloader/internal/media/sources"
+	"media-downloader/internal/set"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrPremiumRequiresCookies = errors.New("premium formats require cookies to be configured")
+	ErrInvalidCookiesProfile  = errors.New("invalid cookies profile")
+)
+
+// Used for every request that doesn't pick a profile of its own
+var cookiesFile string
+
+// Directory of named cookie profiles, <name>.txt each, requests may choose from
+var cookiesDir string
+
+// Profile names can't contain anything that would escape the directory
+var profilePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// SetCookiesFile sets the cookies file used by default, empty disables cookies
+func SetCookiesFile(path string) error {
+	if path != "" {
+		if err := checkCookiesFile(path); err != nil {
+			return err
+		}
+	}
+	cookiesFile = path
+	return nil
+}
+
+// SetCookiesDir sets the directory requests can pick a cookies profile from,
+// empty disables per-request cookies
+func SetCookiesDir(dir string) error {
+	if dir != "" {
+		stat, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("cookies directory: %w", err)
+		}
+		if !stat.IsDir() {
+			return fmt.Errorf("cookies directory %q is not a directory", dir)
+		}
+	}
+	cookiesDir = dir
+	return nil
+}
+
+func checkCookiesFile(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cookies file: %w", err)
+	}
+	if !stat.Mode().IsRegular() {
+		return fmt.Errorf("cookies file %q is not a regular file", path)
+	}
+
+	// Fail now rather than on the first request yt-dlp can't read it
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cookies file: %w", err)
+	}
+	return file.Close()
+}
+
+type cookiesProfileKey struct{}
+
+// WithCookiesProfile makes yt-dlp calls made with the returned context use
+// the named profile from the cookies directory instead of the default file
+func WithCookiesProfile(ctx context.Context, profile string) context.Context {
+	if profile == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, cookiesProfileKey{}, profile)
+}
+
+// cookiesFor returns the cookies file to use for url with ctx, empty when
+// there is none. A profile picked by the request comes first, then a profile
+// named after the url's source, such as instagram.txt, then the default file.
+func cookiesFor(ctx context.Context, url string) (string, error) {
+	profile, _ := ctx.Value(cookiesProfileKey{}).(string)
+	if profile == "" {
+		if path, ok := sourceCookies(url); ok {
+			return path, nil
+		}
+		return cookiesFile, nil
+	}
+
+	if cookiesDir == "" {
+		return "", fmt.Errorf("%w: cookie profiles are not enabled", ErrInvalidCookiesProfile)
+	}
+	if !profilePattern.MatchString(profile) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCookiesProfile, profile)
+	}
+
+	path := filepath.Join(cookiesDir, profile+".txt")
+	if err := checkCookiesFile(path); err != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCookiesProfile, profile)
+	}
+	return path, nil
+}
+
+// sourceCookies returns the profile of the url's source in the cookies
+// directory, if there is one. Sources like Instagram serve hardly anything
+// without logging in, this keeps their cookies apart from the default ones.
+func sourceCookies(url string) (string, bool) {
+	source := sources.IdentifySource(url)
+	if cookiesDir == "" || source == sources.Unknown {
+		return "", false
+	}
+
+	path := filepath.Join(cookiesDir, strings.ToLower(source.String())+".txt")
+	if err := checkCookiesFile(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// HasCookies reports whether yt-dlp calls for url made with ctx are logged in
+func HasCookies(ctx context.Context, url string) bool {
+	path, err := cookiesFor(ctx, url)
+	return err == nil && path != ""
+}
+
+func cookieArgs(ctx context.Context, url string) ([]string, error) {
+	path, err := cookiesFor(ctx, url)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	return []string{"--cookies", path}, nil
+}
+
+// YouTube's "Premium" enhanced bitrate formats, only served to logged in members
+var premiumFormatIDs = func() set.Set[string] {
+	ids := set.New[string]()
+	ids.AddAll("356", "616")
+	return ids
+}()
+
+func isPremiumFormatID(formatID string) bool {
+	return premiumFormatIDs.Contains(formatID)
+}
+
+func isPremium(format Format) bool {
+	return isPremiumFormatID(format.FormatID) || strings.Contains(strings.ToLower(format.FormatNote), "premium")
+}
@@ -0,0 +1,26 @@
+package ytdlp
+
+import "testing"
+
+func TestIsPremium(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		want   bool
+	}{
+		{"premium format id", Format{FormatID: "616", FormatNote: "1080p"}, true},
+		{"other premium format id", Format{FormatID: "356"}, true},
+		{"premium note", Format{FormatID: "137-1", FormatNote: "1080p Premium"}, true},
+		{"premium note in lowercase", Format{FormatID: "999", FormatNote: "premium"}, true},
+		{"regular 1080p", Format{FormatID: "137", FormatNote: "1080p"}, false},
+		{"no note", Format{FormatID: "18"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isPremium(test.format); got != test.want {
+				t.Errorf("isPremium(%+v) = %v, want %v", test.format, got, test.want)
+			}
+		})
+	}
+}
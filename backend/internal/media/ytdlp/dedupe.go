@@ -0,0 +1,90 @@
+package ytdlp
+
+import (
+	"media-downloader/internal/media/info"
+)
+
+// Formats that only differ in bitrate are near duplicates
+type videoKey struct {
+	width, height int
+	fps           float64
+}
+
+type audioKey struct {
+	ext      string
+	channels int
+}
+
+// dedupeVideoFormats keeps the highest bitrate format of each resolution and
+// frame rate, preferring formats anyone can download, and returns how many
+// were dropped
+func dedupeVideoFormats(formats []info.VideoFormat) ([]info.VideoFormat, int) {
+	best := make(map[videoKey]int)
+	kept := make([]info.VideoFormat, 0, len(formats))
+	for _, format := range formats {
+		key := videoKey{
+			width:  format.VideoWidth,
+			height: format.VideoHeight,
+			fps:    format.VideoFPS,
+		}
+
+		i, ok := best[key]
+		if !ok {
+			best[key] = len(kept)
+			kept = append(kept, format)
+			continue
+		}
+		if betterVideo(format, kept[i]) {
+			kept[i] = format
+		}
+	}
+
+	return kept, len(formats) - len(kept)
+}
+
+// dedupeAudioFormats keeps the highest bitrate format of each extension and
+// channel layout, and returns how many were dropped
+func dedupeAudioFormats(formats []info.AudioFormat) ([]info.AudioFormat, int) {
+	best := make(map[audioKey]int)
+	kept := make([]info.AudioFormat, 0, len(formats))
+	for _, format := range formats {
+		key := audioKey{
+			ext:      format.Extension,
+			channels: format.AudioChannels,
+		}
+
+		i, ok := best[key]
+		if !ok {
+			best[key] = len(kept)
+			kept = append(kept, format)
+			continue
+		}
+		if betterFormat(format.Format, format.AudioBitrate, kept[i].Format, kept[i].AudioBitrate) {
+			kept[i] = format
+		}
+	}
+
+	return kept, len(formats) - len(kept)
+}
+
+// Premium formats need cookies and watermarked ones are worse whatever their
+// bitrate, so they only survive when there's nothing else
+func betterVideo(format info.VideoFormat, current info.VideoFormat) bool {
+	if format.HasDRM == current.HasDRM {
+		if format.IsPremium != current.IsPremium {
+			return current.IsPremium
+		}
+		if format.IsWatermarked != current.IsWatermarked {
+			return current.IsWatermarked
+		}
+	}
+	return betterFormat(format.Format, format.VideoBitrate, current.Format, current.VideoBitrate)
+}
+
+// A DRM protected format is dropped later on, so any other format beats it
+func betterFormat(format info.Format, bitrate float64, current info.Format, currentBitrate float64) bool {
+	if format.HasDRM != current.HasDRM {
+		return current.HasDRM
+	}
+	return bitrate > currentBitrate
+}
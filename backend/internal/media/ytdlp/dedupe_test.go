@@ -0,0 +1,188 @@
+package ytdlp
+
+import (
+	"media-downloader/internal/media/codec"
+	"media-downloader/internal/media/info"
+	"slices"
+	"testing"
+)
+
+func testVideo(id string, height int, fps float64, family string, bitrate float64) info.VideoFormat {
+	return info.VideoFormat{
+		VideoWidth:        height * 16 / 9,
+		VideoHeight:       height,
+		VideoFPS:          fps,
+		VideoBitrate:      bitrate,
+		VideoCodecDetails: codec.Details{Family: family},
+		Format:            info.Format{SourceIdentifier: id},
+	}
+}
+
+func testAudio(id string, ext string, channels int, language string, bitrate float64) info.AudioFormat {
+	return info.AudioFormat{
+		AudioChannels: channels,
+		AudioBitrate:  bitrate,
+		Language:      language,
+		Format:        info.Format{SourceIdentifier: id, Extension: ext},
+	}
+}
+
+func TestDedupeVideoFormats(t *testing.T) {
+	drm := testVideo("1080-drm", 1080, 30, "avc", 9000)
+	drm.HasDRM = true
+	premium := testVideo("1080-premium", 1080, 30, "avc", 8000)
+	premium.IsPremium = true
+	watermarked := testVideo("1080-watermarked", 1080, 30, "avc", 8000)
+	watermarked.IsWatermarked = true
+
+	tests := []struct {
+		name    string
+		formats []info.VideoFormat
+		want    []string
+		dropped int
+	}{
+		{
+			name:    "empty",
+			formats: []info.VideoFormat{},
+			want:    []string{},
+		},
+		{
+			name: "highest bitrate wins",
+			formats: []info.VideoFormat{
+				testVideo("1080-low", 1080, 30, "avc", 2000),
+				testVideo("1080-high", 1080, 30, "avc", 4000),
+				testVideo("1080-mid", 1080, 30, "avc", 3000),
+			},
+			want:    []string{"1080-high"},
+			dropped: 2,
+		},
+		{
+			name: "resolution and frame rate kept apart",
+			formats: []info.VideoFormat{
+				testVideo("1080p30", 1080, 30, "avc", 4000),
+				testVideo("1080p60", 1080, 60, "avc", 6000),
+				testVideo("720p30", 720, 30, "avc", 2000),
+			},
+			want: []string{"1080p30", "1080p60", "720p30"},
+		},
+		{
+			name: "codec family is a duplicate",
+			formats: []info.VideoFormat{
+				testVideo("1080p30", 1080, 30, "avc", 4000),
+				testVideo("1080p30-vp9", 1080, 30, "vp9", 3000),
+			},
+			want:    []string{"1080p30"},
+			dropped: 1,
+		},
+		{
+			name: "premium loses to a lower bitrate",
+			formats: []info.VideoFormat{
+				premium,
+				testVideo("1080", 1080, 30, "avc", 4000),
+			},
+			want:    []string{"1080"},
+			dropped: 1,
+		},
+		{
+			name: "watermarked loses to a lower bitrate",
+			formats: []info.VideoFormat{
+				testVideo("1080", 1080, 30, "avc", 4000),
+				watermarked,
+			},
+			want:    []string{"1080"},
+			dropped: 1,
+		},
+		{
+			name: "premium only",
+			formats: []info.VideoFormat{
+				premium,
+			},
+			want: []string{"1080-premium"},
+		},
+		{
+			name: "drm loses to a lower bitrate",
+			formats: []info.VideoFormat{
+				drm,
+				testVideo("1080", 1080, 30, "avc", 4000),
+			},
+			want:    []string{"1080"},
+			dropped: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kept, dropped := dedupeVideoFormats(test.formats)
+
+			got := []string{}
+			for _, format := range kept {
+				got = append(got, format.SourceIdentifier)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if dropped != test.dropped {
+				t.Errorf("got %d dropped, want %d", dropped, test.dropped)
+			}
+		})
+	}
+}
+
+func TestDedupeAudioFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats []info.AudioFormat
+		want    []string
+		dropped int
+	}{
+		{
+			name:    "empty",
+			formats: []info.AudioFormat{},
+			want:    []string{},
+		},
+		{
+			name: "highest bitrate wins",
+			formats: []info.AudioFormat{
+				testAudio("140-drc", "m4a", 2, "en", 96),
+				testAudio("140", "m4a", 2, "en", 128),
+			},
+			want:    []string{"140"},
+			dropped: 1,
+		},
+		{
+			name: "extension and channels kept apart",
+			formats: []info.AudioFormat{
+				testAudio("140", "m4a", 2, "en", 128),
+				testAudio("251", "webm", 2, "en", 160),
+				testAudio("380", "m4a", 6, "en", 384),
+			},
+			want: []string{"140", "251", "380"},
+		},
+		{
+			name: "language is a duplicate",
+			formats: []info.AudioFormat{
+				testAudio("140-de", "m4a", 2, "de", 96),
+				testAudio("140", "m4a", 2, "en", 128),
+			},
+			want:    []string{"140"},
+			dropped: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kept, dropped := dedupeAudioFormats(test.formats)
+
+			got := []string{}
+			for _, format := range kept {
+				got = append(got, format.SourceIdentifier)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if dropped != test.dropped {
+				t.Errorf("got %d dropped, want %d", dropped, test.dropped)
+			}
+		})
+	}
+}
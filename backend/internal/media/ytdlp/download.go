@@ -0,0 +1,147 @@
+package ytdlp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"media-downloader/internal/progress"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Prefix of the progress lines yt-dlp prints to stderr, see progressTemplate
+const progressPrefix = "[progress]"
+
+const progressTemplate = "download:" + progressPrefix + "%(progress.downloaded_bytes)s/%(progress.total_bytes,progress.total_bytes_estimate)s"
+
+type downloadOptions struct {
+	section string
+}
+
+type DownloadOption func(*downloadOptions)
+
+// Section only downloads the part between start and end, in seconds. The cuts
+// land on the nearest keyframes since the streams aren't re-encoded
+func Section(start float64, end float64) DownloadOption {
+	return func(options *downloadOptions) {
+		options.section = fmt.Sprintf("*%s-%s", strconv.FormatFloat(start, 'f', -1, 64), strconv.FormatFloat(end, 'f', -1, 64))
+	}
+}
+
+// Download streams a single format straight from yt-dlp's stdout
+func Download(ctx context.Context, url string, formatID string, opts ...DownloadOption) (io.ReadCloser, error) {
+	options := downloadOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	args := []string{
+		"--format", formatID,
+		"--output", "-",
+		"--quiet",
+		"--no-warnings",
+		"--progress",
+		"--newline",
+		"--progress-template", progressTemplate,
+	}
+	if options.section != "" {
+		args = append(args, "--download-sections", options.section)
+	}
+	cookies, err := cookieArgs(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, cookies...)
+	args = append(args, url)
+
+	stdout, stderr, wait, err := run(ctx, downloadSlots, binaryPath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run yt-dlp: %w", err)
+	}
+
+	// Drain stderr in the background so yt-dlp never blocks on it
+	download := &download{
+		ReadCloser: stdout,
+		ctx:        ctx,
+		wait:       wait,
+		stderrDone: make(chan struct{}),
+	}
+	tracker, _ := progress.From(ctx)
+	go func() {
+		defer close(download.stderrDone)
+		readStderr(stderr, &download.stderr, tracker)
+	}()
+
+	return download, nil
+}
+
+// readStderr hands progress lines to the tracker and keeps everything else
+func readStderr(stderr io.Reader, buffer *bytes.Buffer, tracker *progress.Tracker) {
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, progressPrefix) {
+			buffer.WriteString(line)
+			buffer.WriteByte('\n')
+			continue
+		}
+
+		// The downloaded bytes are counted as they're streamed, only the total matters
+		if tracker == nil {
+			continue
+		}
+		_, total, ok := strings.Cut(strings.TrimPrefix(line, progressPrefix), "/")
+		if !ok {
+			continue
+		}
+		if value, err := strconv.ParseFloat(strings.TrimSpace(total), 64); err == nil && value > 0 {
+			tracker.SetTotal(uint64(value))
+		}
+	}
+
+	// Keep draining in case a line was too long for the scanner
+	_, _ = io.Copy(buffer, stderr)
+}
+
+// download streams yt-dlp's stdout and reaps the process on Close. Only
+// Close tells whether yt-dlp succeeded, reading to the end of stdout doesn't.
+type download struct {
+	io.ReadCloser
+	ctx        context.Context
+	wait       func() error
+	stderr     bytes.Buffer
+	stderrDone chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Close reaps yt-dlp and returns why it failed, if it did. Calling it again
+// returns the same error.
+func (d *download) Close() error {
+	d.closeOnce.Do(func() {
+		d.closeErr = d.close()
+	})
+	return d.closeErr
+}
+
+func (d *download) close() error {
+	closeErr := d.ReadCloser.Close()
+	<-d.stderrDone
+
+	if err := d.wait(); err != nil {
+		if ctxErr := d.ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("yt-dlp cancelled: %w", ctxErr)
+		}
+		if d.stderr.Len() > 0 {
+			return parseStderr(d.stderr.String())
+		}
+		return fmt.Errorf("yt-dlp failed: %w", err)
+	}
+
+	return closeErr
+}
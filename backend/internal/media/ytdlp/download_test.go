@@ -0,0 +1,46 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"media-downloader/internal/media/ytdlp/ytdlptest"
+	"os/exec"
+	"testing"
+)
+
+func TestDownloadCloseReportsFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		stderr  string
+		exitErr error
+		wantErr error
+	}{
+		{name: "success"},
+		{name: "failure after output", stderr: "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable\n", exitErr: &exec.ExitError{}, wantErr: ErrVideoUnavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeYtdlp(t, ytdlptest.Replay("video data", test.stderr, test.exitErr))
+
+			reader, err := Download(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "18")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil || string(body) != "video data" {
+				t.Fatalf("got %q, %v, want the whole output", body, err)
+			}
+
+			err = reader.Close()
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			// Closing again, as a deferred Close would, reports the same
+			if again := reader.Close(); again != err {
+				t.Errorf("got %v closing again, want %v", again, err)
+			}
+		})
+	}
+}
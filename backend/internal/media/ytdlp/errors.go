@@ -0,0 +1,91 @@
+package ytdlp
+
+import (
+	"errors"
+	"fmt"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/transient"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrRateLimited      = errors.New("rate limited by source")
+	ErrVideoUnavailable = errors.New("video unavailable")
+	ErrPrivateVideo     = errors.New("video is private")
+	ErrGeoBlocked       = errors.New("video is not available in this region")
+	ErrLoginRequired    = errors.New("the source requires logging in")
+	ErrNoVideo          = errors.New("the post contains no video or audio")
+)
+
+// Used when the source doesn't tell us how long to back off
+const (
+	defaultRateLimitRetry = 60 * time.Second
+	networkRetry          = 5 * time.Second
+)
+
+var (
+	rateLimitPattern = regexp.MustCompile(`(?i)(HTTP Error 429|Too Many Requests|rate[- ]limit)`)
+	// Failures of the connection rather than the media, worth trying again
+	networkPattern = regexp.MustCompile(`(?i)(timed out|connection (reset|refused|aborted)|temporary failure in name resolution|name or service not known|remote end closed connection|incomplete ?read|HTTP Error 50[0234])`)
+	// The media itself can't be fetched, retrying won't change that
+	loginPattern       = regexp.MustCompile(`(?i)(login required|log in to|need to log in|sign in to confirm|use --cookies)`)
+	noVideoPattern     = regexp.MustCompile(`(?i)(there is no video in this post|no video formats found)`)
+	privatePattern     = regexp.MustCompile(`(?i)(private video|video is private|this video is only available to)`)
+	geoBlockedPattern  = regexp.MustCompile(`(?i)(in your country|geo[- ]?restrict|geo[- ]?block|not available in your (region|location))`)
+	unavailablePattern = regexp.MustCompile(`(?i)(video unavailable|no longer available|has been removed|has been terminated|does not exist|HTTP Error 404|unsupported url)`)
+	// The media exists, just not in the format that was asked for
+	formatPattern = regexp.MustCompile(`(?i)requested format is not available`)
+
+	retryAfterPattern = regexp.MustCompile(`(?i)(?:retry|try again)(?: after| in)? (\d+) ?(s|sec|second|seconds|m|min|minute|minutes)\b`)
+)
+
+// parseStderr turns yt-dlp's error output into a typed error where possible
+func parseStderr(stderr string) error {
+	stderr = strings.TrimSpace(stderr)
+
+	// Checked from most to least specific, geo blocks also say "not available"
+	switch {
+	case noVideoPattern.MatchString(stderr):
+		return fmt.Errorf("%w: %s", ErrNoVideo, stderr)
+	case loginPattern.MatchString(stderr):
+		return fmt.Errorf("%w: %s", ErrLoginRequired, stderr)
+	case privatePattern.MatchString(stderr):
+		return fmt.Errorf("%w: %s", ErrPrivateVideo, stderr)
+	case geoBlockedPattern.MatchString(stderr):
+		return fmt.Errorf("%w: %s", ErrGeoBlocked, stderr)
+	case formatPattern.MatchString(stderr):
+		return fmt.Errorf("%w: %s", info.ErrFormatNotFound, stderr)
+	case unavailablePattern.MatchString(stderr):
+		return fmt.Errorf("%w: %s", ErrVideoUnavailable, stderr)
+	}
+
+	if rateLimitPattern.MatchString(stderr) {
+		return transient.New(fmt.Errorf("%w: %s", ErrRateLimited, stderr), parseRetryAfter(stderr))
+	}
+
+	if networkPattern.MatchString(stderr) {
+		return transient.New(fmt.Errorf("yt-dlp failed: %s", stderr), networkRetry)
+	}
+
+	return fmt.Errorf("yt-dlp failed: %s", stderr)
+}
+
+func parseRetryAfter(stderr string) time.Duration {
+	match := retryAfterPattern.FindStringSubmatch(stderr)
+	if match == nil {
+		return defaultRateLimitRetry
+	}
+
+	amount, err := strconv.Atoi(match[1])
+	if err != nil || amount <= 0 {
+		return defaultRateLimitRetry
+	}
+
+	if strings.HasPrefix(strings.ToLower(match[2]), "m") {
+		return time.Duration(amount) * time.Minute
+	}
+	return time.Duration(amount) * time.Second
+}
@@ -0,0 +1,130 @@
+package ytdlp
+
+import (
+	"errors"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/transient"
+	"testing"
+	"time"
+)
+
+func TestParseStderr(t *testing.T) {
+	tests := []struct {
+		name      string
+		stderr    string
+		want      error
+		transient bool
+		retry     time.Duration
+	}{
+		{
+			name:   "unavailable",
+			stderr: "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable",
+			want:   ErrVideoUnavailable,
+		},
+		{
+			name:   "removed",
+			stderr: "ERROR: [youtube] dQw4w9WgXcQ: This video has been removed by the uploader",
+			want:   ErrVideoUnavailable,
+		},
+		{
+			name:   "unavailable despite a network hiccup",
+			stderr: "WARNING: Connection reset by peer, retrying\nERROR: [youtube] dQw4w9WgXcQ: Video unavailable",
+			want:   ErrVideoUnavailable,
+		},
+		{
+			name:   "geo blocked",
+			stderr: "ERROR: [youtube] dQw4w9WgXcQ: The uploader has not made this video available in your country",
+			want:   ErrGeoBlocked,
+		},
+		{
+			name:   "geo blocked, also not available",
+			stderr: "ERROR: [vimeo] 123456: This video is not available in your region",
+			want:   ErrGeoBlocked,
+		},
+		{
+			name:   "image post",
+			stderr: "ERROR: [Instagram] CxYz123: There is no video in this post",
+			want:   ErrNoVideo,
+		},
+		{
+			name:   "login required",
+			stderr: "ERROR: [Instagram] CxYz123: Requested content is not available, rate-limit reached or login required. Use --cookies to authenticate",
+			want:   ErrLoginRequired,
+		},
+		{
+			name:   "format not available",
+			stderr: "ERROR: [youtube] dQw4w9WgXcQ: Requested format is not available. Use --list-formats for a list of available formats",
+			want:   info.ErrFormatNotFound,
+		},
+		{
+			name:   "not available on its own",
+			stderr: "ERROR: [generic] Some extractor message saying this is not available right now",
+		},
+		{
+			name:   "private",
+			stderr: "ERROR: [youtube] dQw4w9WgXcQ: Private video. Sign in if you've been granted access to this video",
+			want:   ErrPrivateVideo,
+		},
+		{
+			name:      "rate limited with retry",
+			stderr:    "ERROR: HTTP Error 429: Too Many Requests. Try again in 30 seconds",
+			want:      ErrRateLimited,
+			transient: true,
+			retry:     30 * time.Second,
+		},
+		{
+			name:      "rate limited in minutes",
+			stderr:    "ERROR: rate-limit reached, retry after 2 min",
+			want:      ErrRateLimited,
+			transient: true,
+			retry:     2 * time.Minute,
+		},
+		{
+			name:      "rate limited without retry",
+			stderr:    "ERROR: HTTP Error 429: Too Many Requests",
+			want:      ErrRateLimited,
+			transient: true,
+			retry:     defaultRateLimitRetry,
+		},
+		{
+			name:      "connection reset",
+			stderr:    "ERROR: Unable to download webpage: [Errno 104] Connection reset by peer",
+			transient: true,
+			retry:     networkRetry,
+		},
+		{
+			name:      "server error",
+			stderr:    "ERROR: Unable to download API page: HTTP Error 503: Service Unavailable",
+			transient: true,
+			retry:     networkRetry,
+		},
+		{
+			name:   "unknown",
+			stderr: "ERROR: something unexpected happened",
+		},
+	}
+
+	sentinels := []error{ErrVideoUnavailable, ErrGeoBlocked, ErrPrivateVideo, ErrRateLimited, ErrLoginRequired, ErrNoVideo, info.ErrFormatNotFound}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := parseStderr("\n" + test.stderr + "\n")
+			if err == nil {
+				t.Fatal("got nil error")
+			}
+
+			for _, sentinel := range sentinels {
+				if got, want := errors.Is(err, sentinel), sentinel == test.want; got != want {
+					t.Errorf("errors.Is(%v, %v) got %v, want %v", err, sentinel, got, want)
+				}
+			}
+
+			retry, ok := transient.RetryAfter(err)
+			if ok != test.transient {
+				t.Errorf("got transient %v, want %v", ok, test.transient)
+			}
+			if retry != test.retry {
+				t.Errorf("got retry after %v, want %v", retry, test.retry)
+			}
+		})
+	}
+}
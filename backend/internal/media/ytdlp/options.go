@@ -0,0 +1,85 @@
+package ytdlp
+
+import "strings"
+
+// fetchOptions are yt-dlp settings for a single extraction, the zero value
+// matches running yt-dlp without any of them
+type fetchOptions struct {
+	proxy            string
+	geoBypassCountry string
+	formatSort       string
+
+	// Not passed on to yt-dlp, storyboards are always part of its output
+	storyboards bool
+
+	// Skips --check-all-formats, listing formats that may not work
+	skipFormatCheck bool
+}
+
+type Option func(*fetchOptions)
+
+// WithProxy routes the extraction through an HTTP or SOCKS proxy
+func WithProxy(url string) Option {
+	return func(options *fetchOptions) {
+		options.proxy = url
+	}
+}
+
+// WithGeoBypassCountry pretends the request comes from the given two letter
+// country code to get around geo restrictions
+func WithGeoBypassCountry(country string) Option {
+	return func(options *fetchOptions) {
+		options.geoBypassCountry = strings.ToUpper(country)
+	}
+}
+
+// WithFormatSort changes the order yt-dlp ranks formats in, using its
+// --format-sort syntax such as "res,codec:av1"
+func WithFormatSort(sort string) Option {
+	return func(options *fetchOptions) {
+		options.formatSort = sort
+	}
+}
+
+// WithStoryboards lists the storyboard formats, grids of preview images for
+// scrubbing, which are left out by default
+func WithStoryboards() Option {
+	return func(options *fetchOptions) {
+		options.storyboards = true
+	}
+}
+
+// WithoutFormatCheck skips probing whether every format actually works, which
+// is much faster on media with many formats but may list broken ones
+func WithoutFormatCheck() Option {
+	return func(options *fetchOptions) {
+		options.skipFormatCheck = true
+	}
+}
+
+func newFetchOptions(opts []Option) fetchOptions {
+	options := fetchOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+func (o fetchOptions) args() []string {
+	var args []string
+	if o.proxy != "" {
+		args = append(args, "--proxy", o.proxy)
+	}
+	if o.geoBypassCountry != "" {
+		args = append(args, "--xff", o.geoBypassCountry)
+	}
+	if o.formatSort != "" {
+		args = append(args, "--format-sort", o.formatSort)
+	}
+	return args
+}
+
+// cacheKey tells extractions with different options apart
+func (o fetchOptions) cacheKey() string {
+	return o.proxy + "\n" + o.geoBypassCountry + "\n" + o.formatSort
+}
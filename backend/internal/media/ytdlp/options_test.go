@@ -0,0 +1,42 @@
+package ytdlp
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFetchOptionsArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{name: "none"},
+		{name: "proxy", opts: []Option{WithProxy("socks5://127.0.0.1:1080")}, want: []string{"--proxy", "socks5://127.0.0.1:1080"}},
+		{name: "geo bypass uppercased", opts: []Option{WithGeoBypassCountry("se")}, want: []string{"--xff", "SE"}},
+		{name: "format sort", opts: []Option{WithFormatSort("res,codec:av1")}, want: []string{"--format-sort", "res,codec:av1"}},
+		{
+			name: "all",
+			opts: []Option{WithFormatSort("res"), WithGeoBypassCountry("US"), WithProxy("http://proxy:3128")},
+			want: []string{"--proxy", "http://proxy:3128", "--xff", "US", "--format-sort", "res"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := newFetchOptions(test.opts).args(); !slices.Equal(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFetchOptionsCacheKey(t *testing.T) {
+	plain := newFetchOptions(nil).cacheKey()
+	proxied := newFetchOptions([]Option{WithProxy("http://proxy:3128")}).cacheKey()
+	sorted := newFetchOptions([]Option{WithFormatSort("http://proxy:3128")}).cacheKey()
+
+	if plain == proxied || proxied == sorted {
+		t.Errorf("got the same cache key for different options: %q, %q, %q", plain, proxied, sorted)
+	}
+}
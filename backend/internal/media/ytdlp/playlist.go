@@ -0,0 +1,89 @@
+package ytdlp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/media/sources"
+)
+
+// StreamPlaylist lists the entries of a playlist without resolving each one,
+// calling yield as soon as yt-dlp prints an entry
+func StreamPlaylist(ctx context.Context, url string, source sources.Source, yield func(info.PlaylistEntry) error) (err error) {
+	// Large playlists take longer than a metadata extraction is allowed to,
+	// so only the caller's context bounds the stream. Cancelling stops
+	// yt-dlp early when the consumer gives up.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := []string{
+		"--ignore-errors",
+		"--flat-playlist",
+		"--dump-json",
+		"--quiet",
+	}
+	cookies, err := cookieArgs(ctx, url)
+	if err != nil {
+		return err
+	}
+	args = append(args, cookies...)
+	args = append(args, url)
+
+	var stdout, stderr io.ReadCloser
+	var wait func() error
+	if stdout, stderr, wait, err = run(ctx, extractSlots, binaryPath, args...); err != nil {
+		return fmt.Errorf("failed to run yt-dlp: %w", err)
+	}
+
+	// Drain stderr in the background so yt-dlp never blocks on it
+	var stderrBuffer bytes.Buffer
+	stderrDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&stderrBuffer, stderr)
+		close(stderrDone)
+	}()
+
+	// Each line is a single entry
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry PlaylistEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			cancel()
+			break
+		}
+
+		if err = yield(info.PlaylistEntry{
+			Url:      entry.entryURL(),
+			Title:    entry.Title,
+			Duration: entry.Duration,
+			Source:   source,
+		}); err != nil {
+			cancel()
+			break
+		}
+	}
+	if err == nil {
+		err = scanner.Err()
+	}
+
+	// Wait for yt-dlp to finish, the scan error takes precedence
+	_, _ = io.Copy(io.Discard, stdout)
+	<-stderrDone
+	waitErr := wait()
+	if err != nil {
+		return fmt.Errorf("failed to read playlist: %w", err)
+	}
+	if waitErr != nil {
+		if stderrBuffer.Len() > 0 {
+			return parseStderr(stderrBuffer.String())
+		}
+		return fmt.Errorf("yt-dlp failed: %w", waitErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package ytdlp
+
+import (
+	"fmt"
+	"math"
+)
+
+// Common resolutions by their long and short side, a video matching the long
+// side of one but with fewer lines is letterboxed and labelled as the full size
+var standardResolutions = []struct{ long, short int }{
+	{256, 144},
+	{426, 240},
+	{640, 360},
+	{854, 480},
+	{1280, 720},
+	{1920, 1080},
+	{2560, 1440},
+	{3840, 2160},
+	{7680, 4320},
+}
+
+// qualityLabel returns a label like "1080p" or "720p60". Portrait videos are
+// labelled by their width so a vertical 1080x1920 video is also "1080p"
+func qualityLabel(width int, height int, fps float64) string {
+	short, long := height, width
+	if width > 0 && width < height {
+		short, long = width, height
+	}
+	if short <= 0 {
+		return ""
+	}
+
+	for _, resolution := range standardResolutions {
+		if resolution.long == long && short < resolution.short {
+			short = resolution.short
+			break
+		}
+	}
+
+	label := fmt.Sprintf("%dp", short)
+	if fps > 30 {
+		label += fmt.Sprintf("%d", int(math.Round(fps)))
+	}
+	return label
+}
+
+// aspectRatio prefers yt-dlp's own value and otherwise derives it from the
+// dimensions, rounded to two decimals
+func aspectRatio(format Format) float64 {
+	if format.AspectRatio > 0 {
+		return format.AspectRatio
+	}
+	if format.Width <= 0 || format.Height <= 0 {
+		return 0
+	}
+	return math.Round(float64(format.Width)/float64(format.Height)*100) / 100
+}
@@ -0,0 +1,48 @@
+package ytdlp
+
+import "testing"
+
+func TestQualityLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		fps    float64
+		want   string
+	}{
+		{name: "1080p", width: 1920, height: 1080, fps: 30, want: "1080p"},
+		{name: "high frame rate", width: 1280, height: 720, fps: 59.94, want: "720p60"},
+		{name: "letterboxed", width: 1920, height: 800, fps: 24, want: "1080p"},
+		{name: "portrait", width: 1080, height: 1920, fps: 30, want: "1080p"},
+		{name: "non-standard", width: 1000, height: 500, fps: 25, want: "500p"},
+		{name: "unknown height", width: 0, height: 0, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := qualityLabel(test.width, test.height, test.fps); got != test.want {
+				t.Errorf("qualityLabel(%d, %d, %v) = %q, want %q", test.width, test.height, test.fps, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAspectRatio(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		want   float64
+	}{
+		{name: "reported", format: Format{AspectRatio: 2.39, Width: 1920, Height: 1080}, want: 2.39},
+		{name: "derived", format: Format{Width: 1920, Height: 1080}, want: 1.78},
+		{name: "unknown", format: Format{Width: 1920}, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := aspectRatio(test.format); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
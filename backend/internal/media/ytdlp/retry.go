@@ -0,0 +1,53 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"media-downloader/internal/transient"
+	"time"
+)
+
+// How often a failing yt-dlp call is attempted and how long to wait before
+// the first retry, the delay doubles after every attempt
+var (
+	retryAttempts  = 3
+	retryBaseDelay = time.Second
+)
+
+func SetRetry(attempts int, baseDelay time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	retryAttempts = attempts
+	retryBaseDelay = baseDelay
+}
+
+// withRetry calls f until it succeeds, fails permanently or runs out of
+// attempts, returning the last error
+func withRetry[T any](ctx context.Context, f func() (T, error)) (result T, err error) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		if result, err = f(); err == nil || attempt >= retryAttempts || !isRetryable(ctx, err) {
+			return result, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// Only failures yt-dlp reported as transient are worth another attempt, a
+// timeout or cancellation means the caller has run out of time
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	_, ok := transient.RetryAfter(err)
+	return ok
+}
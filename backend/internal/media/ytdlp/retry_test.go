@@ -0,0 +1,75 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"media-downloader/internal/transient"
+	"testing"
+	"time"
+)
+
+func setTestRetry(t *testing.T, attempts int) {
+	t.Helper()
+	previousAttempts, previousDelay := retryAttempts, retryBaseDelay
+	SetRetry(attempts, time.Millisecond)
+	t.Cleanup(func() { retryAttempts, retryBaseDelay = previousAttempts, previousDelay })
+}
+
+func TestWithRetry(t *testing.T) {
+	failTransiently := transient.New(errors.New("connection reset"), time.Second)
+	failPermanently := errors.New("video unavailable")
+
+	tests := []struct {
+		name         string
+		failures     []error
+		wantErr      error
+		wantAttempts int
+	}{
+		{name: "first attempt succeeds", wantAttempts: 1},
+		{name: "transient failures recover", failures: []error{failTransiently, failTransiently}, wantAttempts: 3},
+		{name: "permanent failure", failures: []error{failPermanently}, wantErr: failPermanently, wantAttempts: 1},
+		{name: "out of attempts", failures: []error{failTransiently, failTransiently, failTransiently, failTransiently}, wantErr: failTransiently, wantAttempts: 3},
+		{name: "timeout", failures: []error{context.DeadlineExceeded}, wantErr: context.DeadlineExceeded, wantAttempts: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setTestRetry(t, 3)
+
+			attempts := 0
+			result, err := withRetry(context.Background(), func() (int, error) {
+				attempts++
+				if attempts <= len(test.failures) {
+					return 0, test.failures[attempts-1]
+				}
+				return attempts, nil
+			})
+
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+			if err == nil && result != attempts {
+				t.Errorf("got result %d, want that of attempt %d", result, attempts)
+			}
+			if attempts != test.wantAttempts {
+				t.Errorf("got %d attempts, want %d", attempts, test.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestWithRetryCancelled(t *testing.T) {
+	setTestRetry(t, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	_, err := withRetry(ctx, func() (int, error) {
+		attempts++
+		cancel()
+		return 0, transient.New(errors.New("connection reset"), time.Second)
+	})
+
+	if err == nil || attempts != 1 {
+		t.Errorf("got error %v after %d attempts, want the first failure only", err, attempts)
+	}
+}
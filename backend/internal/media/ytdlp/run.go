@@ -1,13 +1,107 @@
 package ytdlp
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"media-downloader/internal/metrics"
 	"os/exec"
+	"runtime"
+	"sync"
+	"time"
 )
 
-func run(bin string, args ...string) (stdout io.ReadCloser, stderr io.ReadCloser, waitFun func() error, err error) {
-	cmd := exec.Command(bin, args...)
+// Looked up on the PATH unless configured as an explicit path
+var binaryPath = "yt-dlp"
+
+func SetBinaryPath(path string) {
+	binaryPath = path
+}
+
+// Proxy every run goes through, empty leaves yt-dlp to its environment
+var proxyURL string
+
+// SetProxy routes every yt-dlp run through the given HTTP or SOCKS proxy. It
+// overrides the proxy environment variables while a per-extraction WithProxy
+// still takes precedence over it.
+func SetProxy(url string) {
+	proxyURL = url
+}
+
+// CheckBinary reports whether the configured yt-dlp binary exists and is executable
+func CheckBinary() error {
+	_, err := resolveBinary(binaryPath)
+	return err
+}
+
+func resolveBinary(bin string) (string, error) {
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		return "", fmt.Errorf("yt-dlp binary %q not found or not executable: %w", bin, err)
+	}
+	return path, nil
+}
+
+// Default upper bound on metadata extraction, downloads aren't limited
+var timeout = 60 * time.Second
+
+func SetTimeout(d time.Duration) {
+	timeout = d
+}
+
+// Bound how many yt-dlp processes run at once. Downloads may run for a long
+// time, so they have slots of their own and can't hold up extractions. A
+// merged download takes two of them.
+var (
+	extractSlots  = make(chan struct{}, runtime.NumCPU())
+	downloadSlots = make(chan struct{}, 2*runtime.NumCPU())
+)
+
+// SetConcurrency limits the number of simultaneous yt-dlp processes extracting
+// and downloading, it must be called before any are started. The download
+// limit must be at least 2 for merged downloads, the config validates both.
+func SetConcurrency(extractLimit int, downloadLimit int) {
+	extractSlots = make(chan struct{}, extractLimit)
+	downloadSlots = make(chan struct{}, downloadLimit)
+}
+
+// InUse returns the number of yt-dlp processes currently running
+func InUse() int {
+	return len(extractSlots) + len(downloadSlots)
+}
+
+// Grace period for the pipes to close after the process has been killed
+const waitDelay = 5 * time.Second
+
+// Runner starts yt-dlp processes. The default one executes the binary, others
+// can stand in for it, replaying captured output for instance
+type Runner interface {
+	Run(ctx context.Context, bin string, args ...string) (stdout io.ReadCloser, stderr io.ReadCloser, wait func() error, err error)
+}
+
+var runner Runner = execRunner{}
+
+// SetRunner replaces how yt-dlp processes are started, nil restores running
+// the binary
+func SetRunner(r Runner) {
+	if r == nil {
+		r = execRunner{}
+	}
+	runner = r
+}
+
+type execRunner struct{}
+
+func (execRunner) Run(ctx context.Context, bin string, args ...string) (stdout io.ReadCloser, stderr io.ReadCloser, wait func() error, err error) {
+	// Fail with a clear message rather than a cryptic exec error
+	path, err := resolveBinary(bin)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The process is killed as soon as ctx is done
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.WaitDelay = waitDelay
 	stdout, err = cmd.StdoutPipe()
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("stdout pipe failed: %w", err)
@@ -21,3 +115,37 @@ func run(bin string, args ...string) (stdout io.ReadCloser, stderr io.ReadCloser
 	}
 	return stdout, stderr, cmd.Wait, nil
 }
+
+// run starts yt-dlp once one of the given slots is free
+func run(ctx context.Context, slots chan struct{}, bin string, args ...string) (stdout io.ReadCloser, stderr io.ReadCloser, waitFun func() error, err error) {
+	// Wait for a free slot, held until the process has been waited for
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, nil, fmt.Errorf("waiting for a free yt-dlp slot: %w", ctx.Err())
+	}
+	var releaseOnce sync.Once
+	release := func() {
+		releaseOnce.Do(func() { <-slots })
+	}
+
+	// Later arguments win, so a per-extraction proxy overrides this one
+	if proxyURL != "" {
+		args = append([]string{"--proxy", proxyURL}, args...)
+	}
+	stdout, stderr, cmdWait, err := runner.Run(ctx, bin, args...)
+	if err != nil {
+		release()
+		return nil, nil, nil, err
+	}
+
+	metrics.YtdlpInvocations.Inc()
+	started := time.Now()
+	wait := func() error {
+		defer release()
+		err := cmdWait()
+		metrics.YtdlpDuration.Observe(time.Since(started).Seconds())
+		return err
+	}
+	return stdout, stderr, wait, nil
+}
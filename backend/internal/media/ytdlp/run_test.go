@@ -0,0 +1,49 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	fakeBinary(t, "exec sleep 30\n")
+	previousExtract, previousDownload := extractSlots, downloadSlots
+	SetConcurrency(1, 2)
+	t.Cleanup(func() { extractSlots, downloadSlots = previousExtract, previousDownload })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Running downloads don't hold up an extraction
+	var waits []func() error
+	for _, slots := range []chan struct{}{downloadSlots, downloadSlots, extractSlots} {
+		_, _, wait, err := run(ctx, slots, "yt-dlp")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		waits = append(waits, wait)
+	}
+	if got := InUse(); got != 3 {
+		t.Errorf("got %d processes in use, want 3", got)
+	}
+
+	// Another process of either kind waits for a free slot until it runs out of time
+	for _, slots := range []chan struct{}{extractSlots, downloadSlots} {
+		waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		if _, _, _, err := run(waitCtx, slots, "yt-dlp"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		waitCancel()
+	}
+
+	// Slots are only freed once the processes were waited for
+	cancel()
+	for _, wait := range waits {
+		_ = wait()
+	}
+	if got := InUse(); got != 0 {
+		t.Errorf("got %d processes in use after waiting, want 0", got)
+	}
+}
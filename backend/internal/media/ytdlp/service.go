@@ -1,129 +1,374 @@
 package ytdlp
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"media-downloader/internal/media/codec"
 	"media-downloader/internal/media/info"
 	"media-downloader/internal/media/sources"
+	"strings"
+	"time"
 )
 
-func GetAvailableFormats(url string) (media *info.Media, err error) {
+func GetAvailableFormats(ctx context.Context, url string, source sources.Source, opts ...Option) (media *info.Media, err error) {
 	// Get the raw media mediaInfo
+	options := newFetchOptions(opts)
 	var mediaInfo *MediaInfo
-	mediaInfo, err = getRawMediaInfo(url)
+	mediaInfo, err = getRawMediaInfo(ctx, url, !options.skipFormatCheck, options)
 	if err != nil {
 		return nil, err
 	}
 
-	return &info.Media{
-		Url:          url,
-		Title:        mediaInfo.Title,
-		Duration:     mediaInfo.Duration,
-		VideoFormats: getVideoFormats(mediaInfo.Formats),
-		AudioFormats: getAudioFormats(mediaInfo.Formats),
-	}, nil
+	// Instagram reports image posts like any other, just without formats
+	if source == sources.Instagram && len(mediaInfo.Formats) == 0 {
+		return nil, ErrNoVideo
+	}
+
+	media = newMedia(url, mediaInfo)
+
+	// Formats the check found broken are never offered, without the check
+	// nothing is known about them
+	formats := mediaInfo.Formats
+	if !options.skipFormatCheck {
+		var broken int
+		formats, broken = workingFormats(formats)
+		media.Stats().NonWorking += broken
+	}
+
+	// Drop near duplicate qualities, counting them in the filter stats
+	var videoDuplicates, audioDuplicates int
+	media.VideoFormats, videoDuplicates = dedupeVideoFormats(getVideoFormats(formats, source))
+	if len(media.VideoFormats) == 0 {
+		// Some sources only serve video with the audio already muxed in
+		media.VideoFormats, videoDuplicates = dedupeVideoFormats(getProgressiveFormats(formats, source))
+	}
+
+	media.AudioFormats, audioDuplicates = dedupeAudioFormats(getAudioFormats(formats, source))
+	media.Stats().Duplicate += videoDuplicates + audioDuplicates
+
+	if options.storyboards {
+		media.Storyboards = getStoryboards(mediaInfo.Formats, source)
+	}
+
+	return media, nil
+}
+
+// GetMetadata fetches the media's metadata without checking which formats
+// actually work, which is considerably faster. The formats are left empty.
+func GetMetadata(ctx context.Context, url string, opts ...Option) (*info.Media, error) {
+	mediaInfo, err := getRawMediaInfo(ctx, url, false, newFetchOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	media := newMedia(url, mediaInfo)
+	media.VideoFormats = make([]info.VideoFormat, 0)
+	media.AudioFormats = make([]info.AudioFormat, 0)
+	return media, nil
+}
+
+// newMedia fills in everything but the formats
+func newMedia(url string, mediaInfo *MediaInfo) *info.Media {
+	media := &info.Media{
+		ID:        mediaInfo.ID,
+		Url:       url,
+		Title:     mediaInfo.Title,
+		Duration:  mediaInfo.Duration,
+		IsLive:    mediaInfo.IsLive || mediaInfo.LiveStatus == "is_live",
+		Thumbnail: getThumbnail(mediaInfo),
+		Subtitles: getSubtitles(mediaInfo),
+		Chapters:  getChapters(mediaInfo),
+
+		Uploader:    mediaInfo.Uploader,
+		UploaderURL: mediaInfo.UploaderURL,
+		ViewCount:   mediaInfo.ViewCount,
+		Description: mediaInfo.Description,
+		UploadDate:  mediaInfo.UploadDate,
+		UploadedAt:  parseUploadDate(mediaInfo.UploadDate),
+	}
+	media.SortSubtitles()
+
+	return media
+}
+
+// getRawMediaInfo extracts the media info, retrying transient failures and
+// sharing a single extraction between concurrent requests for the same URL.
+// Successful extractions are cached for a while. Without checkFormats the
+// formats yt-dlp lists may not all work.
+func getRawMediaInfo(ctx context.Context, url string, checkFormats bool, options fetchOptions) (*MediaInfo, error) {
+	// Playlist parameters would have yt-dlp extract the whole playlist
+	url = sources.CanonicalizeURL(url)
+
+	key, err := cacheKey(ctx, url, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// A checked extraction serves metadata requests just as well
+	keys := []string{key}
+	if !checkFormats {
+		key = metadataKeyPrefix + key
+		keys = append(keys, key)
+	}
+	if mediaInfo, ok := cacheGet(keys...); ok {
+		return mediaInfo, nil
+	}
+
+	return coalesce(ctx, key, func(ctx context.Context) (*MediaInfo, error) {
+		mediaInfo, err := withRetry(ctx, func() (*MediaInfo, error) {
+			return fetchRawMediaInfo(ctx, url, checkFormats, options)
+		})
+		if err == nil {
+			cacheSet(key, mediaInfo)
+		}
+		return mediaInfo, err
+	})
+}
+
+func fetchRawMediaInfo(ctx context.Context, url string, checkFormats bool, options fetchOptions) (mediaInfo *MediaInfo, err error) {
+	err = extract(ctx, url, checkFormats, options, func(stdout io.Reader) error {
+		if err := json.NewDecoder(stdout).Decode(&mediaInfo); err != nil {
+			return fmt.Errorf("failed to parse yt-dlp output: %w", err)
+		}
+		if mediaInfo == nil {
+			return fmt.Errorf("yt-dlp returned no media info")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mediaInfo, nil
+}
+
+// RawJSON returns yt-dlp's output for url exactly as it reported it, for
+// debugging. It is neither cached nor retried.
+func RawJSON(ctx context.Context, url string, opts ...Option) (json.RawMessage, error) {
+	var raw json.RawMessage
+	err := extract(ctx, url, true, newFetchOptions(opts), func(stdout io.Reader) error {
+		if err := json.NewDecoder(stdout).Decode(&raw); err != nil {
+			return fmt.Errorf("failed to parse yt-dlp output: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return raw, nil
 }
 
-func getRawMediaInfo(url string) (mediaInfo *MediaInfo, err error) {
+// extract runs yt-dlp to dump the info of url as JSON, handing its output to
+// read as it's produced
+func extract(ctx context.Context, url string, checkFormats bool, options fetchOptions, read func(stdout io.Reader) error) (err error) {
+	// Every attempt gets the full timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	// Run yt-dlp
 	var stdout, stderr io.ReadCloser
 	var wait func() error
-	if stdout, stderr, wait, err = run(
-		"yt-dlp",
+	args := []string{
 		"--ignore-errors",
-		"--check-all-formats",
 		"--dump-single-json",
 		"--quiet",
-		url,
-	); err != nil {
-		return nil, fmt.Errorf("failed to run yt-dlp: %w", err)
 	}
-
-	// Read the output
-	stdoutBytes, err := io.ReadAll(stdout)
+	if checkFormats {
+		args = append(args, "--check-all-formats")
+	}
+	args = append(args, options.args()...)
+	cookies, err := cookieArgs(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read stdout: %w", err)
+		return err
+	}
+	args = append(args, cookies...)
+	args = append(args, url)
+
+	if stdout, stderr, wait, err = run(ctx, extractSlots, binaryPath, args...); err != nil {
+		return fmt.Errorf("failed to run yt-dlp: %w", err)
+	}
+
+	// Drain stderr in the background so yt-dlp never blocks writing to it
+	// while stdout is being read
+	var stderrBuffer bytes.Buffer
+	var stderrErr error
+	stderrDone := make(chan struct{})
+	go func() {
+		_, stderrErr = io.Copy(&stderrBuffer, stderr)
+		close(stderrDone)
+	}()
+
+	// Read straight from the pipe so large output is never buffered whole,
+	// then drain the rest as the process can't exit while its pipes are full
+	readErr := read(stdout)
+	_, stdoutErr := io.Copy(io.Discard, stdout)
+	<-stderrDone
+	stderrBytes := stderrBuffer.Bytes()
+
+	// Wait for yt-dlp to finish, even when reading failed, to free its slot
+	err = wait()
+	if stdoutErr != nil {
+		return fmt.Errorf("failed to read stdout: %w", stdoutErr)
+	}
+	if stderrErr != nil {
+		return fmt.Errorf("failed to read stderr: %w", stderrErr)
 	}
-	stderrBytes, err := io.ReadAll(stderr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read stderr: %w", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("yt-dlp cancelled: %w", ctxErr)
+		}
+		if len(stderrBytes) > 0 {
+			return parseStderr(string(stderrBytes))
+		}
+		return fmt.Errorf("yt-dlp failed: %w", err)
 	}
 
-	// Wait for yt-dlp to finish
-	if err = wait(); err != nil {
-		return nil, fmt.Errorf("yt-dlp failed: %w", err)
+	// yt-dlp writes warnings to stderr even when it succeeds, so stderr only
+	// explains output that is missing
+	if readErr != nil {
+		if len(stderrBytes) > 0 {
+			return parseStderr(string(stderrBytes))
+		}
+		return readErr
 	}
 
-	// Check for errors
 	if len(stderrBytes) > 0 {
-		return nil, fmt.Errorf("yt-dlp failed: %s", stderrBytes)
+		slog.WarnContext(ctx, "yt-dlp reported warnings", "url", url, "stderr", strings.TrimSpace(string(stderrBytes)))
 	}
 
-	// Parse the output
-	if err := json.Unmarshal(stdoutBytes, &mediaInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse yt-dlp output: %w", err)
+	return nil
+}
+
+func getVideoFormats(formats []Format, source sources.Source) []info.VideoFormat {
+	var videoFormats = make([]info.VideoFormat, 0)
+	for _, format := range formats {
+		// Only keep video-only formats, the audio is picked separately
+		if !isVideoOnly(format) {
+			continue
+		}
+
+		videoFormat := newVideoFormat(format, source)
+		// Reddit's DASH streams only report a total bitrate, without it
+		// cleaning would drop them before they can be merged with audio
+		if source == sources.Reddit && videoFormat.VideoBitrate <= 0 {
+			videoFormat.VideoBitrate = format.Tbr
+		}
+		videoFormats = append(videoFormats, videoFormat)
 	}
 
-	return mediaInfo, nil
+	return videoFormats
 }
 
-func getVideoFormats(formats []Format) []info.VideoFormat {
+// getProgressiveFormats returns the formats carrying both video and audio
+func getProgressiveFormats(formats []Format, source sources.Source) []info.VideoFormat {
 	var videoFormats = make([]info.VideoFormat, 0)
 	for _, format := range formats {
-		// Ensure there is no audio
-		if format.Acodec != "none" || format.Acodec == "" {
+		if !hasCodec(format.Vcodec) || !hasCodec(format.Acodec) {
 			continue
 		}
 
-		// Ensure there is a video codec
-		if format.Vcodec == "none" || format.Vcodec == "" {
+		videoFormat := newVideoFormat(format, source)
+		videoFormat.HasAudio = true
+		// The total bitrate is all yt-dlp knows for some muxed formats
+		if videoFormat.VideoBitrate <= 0 {
+			videoFormat.VideoBitrate = format.Tbr
+		}
+		videoFormats = append(videoFormats, videoFormat)
+	}
+
+	return videoFormats
+}
+
+func newVideoFormat(format Format, source sources.Source) info.VideoFormat {
+	return info.VideoFormat{
+		VideoCodec:        format.Vcodec,
+		VideoCodecDetails: codec.Parse(format.Vcodec),
+		VideoBitrate:      format.Vbr,
+		VideoWidth:        int(format.Width),
+		VideoHeight:       int(format.Height),
+		VideoFPS:          format.Fps,
+		QualityLabel:      qualityLabel(int(format.Width), int(format.Height), format.Fps),
+		AspectRatio:       aspectRatio(format),
+		IsPremium:         isPremium(format),
+		IsWatermarked:     source == sources.TikTok && isWatermarked(format),
+
+		Format: info.Format{
+			Extension:       format.Ext,
+			Size:            uint64(max(format.Filesize, format.FilesizeApprox)),
+			SizeApproximate: format.Filesize <= 0 && format.FilesizeApprox > 0,
+			HasDRM:          format.HasDrm,
+			Protocol:        format.Protocol,
+			Container:       format.Container,
+			DirectURL:       format.URL,
+			Headers:         format.HTTPHeaders,
+
+			Source:           source,
+			SourceIdentifier: format.FormatID,
+		},
+	}
+}
+
+// getStoryboards returns the formats made of preview image grids
+func getStoryboards(formats []Format, source sources.Source) []info.Storyboard {
+	var storyboards = make([]info.Storyboard, 0)
+	for _, format := range formats {
+		if !isStoryboard(format) {
 			continue
 		}
 
-		videoFormats = append(videoFormats, info.VideoFormat{
-			VideoCodec:   format.Vcodec,
-			VideoBitrate: format.Vbr,
-			VideoWidth:   int(format.Width),
-			VideoHeight:  int(format.Height),
-			VideoFPS:     format.Fps,
+		storyboards = append(storyboards, info.Storyboard{
+			Width:   int(format.Width),
+			Height:  int(format.Height),
+			Rows:    int(format.Rows),
+			Columns: int(format.Columns),
+			FPS:     format.Fps,
 
 			Format: info.Format{
 				Extension: format.Ext,
 				Size:      uint64(max(format.Filesize, format.FilesizeApprox)),
+				Protocol:  format.Protocol,
+				DirectURL: format.URL,
+				Headers:   format.HTTPHeaders,
 
-				Source:           sources.YouTube,
+				Source:           source,
 				SourceIdentifier: format.FormatID,
 			},
 		})
 	}
 
-	return videoFormats
+	return storyboards
 }
 
-func getAudioFormats(formats []Format) []info.AudioFormat {
+func getAudioFormats(formats []Format, source sources.Source) []info.AudioFormat {
 	var audioFormats = make([]info.AudioFormat, 0)
 	for _, format := range formats {
-		// Ensure there is no video
-		if format.Vcodec != "none" || format.Vcodec == "" {
-			continue
-		}
-
-		// Ensure there is an audio codec
-		if format.Acodec == "none" || format.Acodec == "" {
+		// Only keep audio-only formats
+		if !isAudioOnly(format) {
 			continue
 		}
 
 		audioFormats = append(audioFormats, info.AudioFormat{
-			AudioCodec:      format.Acodec,
-			AudioBitrate:    format.Abr,
-			AudioSampleRate: format.Asr,
+			AudioCodec:        format.Acodec,
+			AudioCodecDetails: codec.Parse(format.Acodec),
+			AudioBitrate:      format.Abr,
+			AudioSampleRate:   format.Asr,
+			AudioChannels:     int(format.AudioChannels),
+			Language:          format.Language,
 
 			Format: info.Format{
-				Extension: format.Ext,
-				Size:      uint64(max(format.Filesize, format.FilesizeApprox)),
+				Extension:       format.Ext,
+				Size:            uint64(max(format.Filesize, format.FilesizeApprox)),
+				SizeApproximate: format.Filesize <= 0 && format.FilesizeApprox > 0,
+				HasDRM:          format.HasDrm,
+				Protocol:        format.Protocol,
+				Container:       format.Container,
+				DirectURL:       format.URL,
+				Headers:         format.HTTPHeaders,
 
-				Source:           sources.YouTube,
+				Source:           source,
 				SourceIdentifier: format.FormatID,
 			},
 		})
@@ -132,9 +377,128 @@ func getAudioFormats(formats []Format) []info.AudioFormat {
 	return audioFormats
 }
 
+// hasCodec reports whether yt-dlp named an actual codec, "none" means the
+// stream is absent and an empty value means yt-dlp doesn't know
+func hasCodec(codec string) bool {
+	return codec != "" && codec != "none"
+}
+
+func isVideoOnly(format Format) bool {
+	return format.Acodec == "none" && hasCodec(format.Vcodec)
+}
+
+// workingFormats leaves out the formats --check-all-formats found broken,
+// returning how many those were. Formats without the flag are kept, their
+// state is simply unknown. The cached formats are left untouched.
+func workingFormats(formats []Format) ([]Format, int) {
+	working := make([]Format, 0, len(formats))
+	for _, format := range formats {
+		if format.Working != nil && !*format.Working {
+			continue
+		}
+		working = append(working, format)
+	}
+	return working, len(formats) - len(working)
+}
+
+func isStoryboard(format Format) bool {
+	return format.FormatNote == "storyboard" || format.Ext == "mhtml"
+}
+
+func isAudioOnly(format Format) bool {
+	return format.Vcodec == "none" && hasCodec(format.Acodec)
+}
+
 func max(a, b int64) int64 {
 	if a > b {
 		return a
 	}
 	return b
 }
+
+// getThumbnail picks the highest resolution thumbnail, using yt-dlp's own
+// preference for thumbnails of unknown size
+func getThumbnail(mediaInfo *MediaInfo) string {
+	var best *Thumbnail
+	for i, thumbnail := range mediaInfo.Thumbnails {
+		if thumbnail.URL == "" {
+			continue
+		}
+
+		if best == nil {
+			best = &mediaInfo.Thumbnails[i]
+			continue
+		}
+
+		resolution := thumbnail.Width * thumbnail.Height
+		bestResolution := best.Width * best.Height
+		if resolution > bestResolution || (resolution == bestResolution && thumbnail.Preference > best.Preference) {
+			best = &mediaInfo.Thumbnails[i]
+		}
+	}
+
+	if best == nil {
+		return mediaInfo.Thumbnail
+	}
+	return best.URL
+}
+
+func getChapters(mediaInfo *MediaInfo) []info.Chapter {
+	var chapters = make([]info.Chapter, 0, len(mediaInfo.Chapters))
+	for _, chapter := range mediaInfo.Chapters {
+		chapters = append(chapters, info.Chapter{
+			Title:     chapter.Title,
+			StartTime: chapter.StartTime,
+			EndTime:   chapter.EndTime,
+		})
+	}
+
+	return chapters
+}
+
+func getSubtitles(mediaInfo *MediaInfo) []info.Subtitle {
+	var subtitles = make([]info.Subtitle, 0)
+	for language, formats := range mediaInfo.Subtitles {
+		subtitles = append(subtitles, getSubtitle(language, formats, false))
+	}
+	for language, formats := range mediaInfo.AutomaticCaptions {
+		subtitles = append(subtitles, getSubtitle(language, formats, true))
+	}
+
+	return subtitles
+}
+
+func getSubtitle(language string, formats []SubtitleFormat, automatic bool) info.Subtitle {
+	subtitle := info.Subtitle{
+		Language:    language,
+		IsAutomatic: automatic,
+		Formats:     make([]info.SubtitleFormat, 0, len(formats)),
+	}
+
+	for _, format := range formats {
+		if subtitle.Name == "" {
+			subtitle.Name = format.Name
+		}
+
+		subtitle.Formats = append(subtitle.Formats, info.SubtitleFormat{
+			Extension: format.Ext,
+			DirectURL: format.URL,
+		})
+	}
+
+	return subtitle
+}
+
+// parseUploadDate parses yt-dlp's YYYYMMDD upload date, returning nil when
+// it's missing or malformed
+func parseUploadDate(date string) *time.Time {
+	if date == "" {
+		return nil
+	}
+
+	parsed, err := time.Parse("20060102", date)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
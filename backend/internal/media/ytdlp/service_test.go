@@ -0,0 +1,492 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/media/sources"
+	"media-downloader/internal/media/ytdlp/ytdlptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeYtdlp runs process in place of yt-dlp for the rest of the test.
+// Caching and retries are turned off so every call reaches it.
+func fakeYtdlp(t *testing.T, process ytdlptest.Process) *ytdlptest.Runner {
+	t.Helper()
+
+	previousAttempts, previousDelay := retryAttempts, retryBaseDelay
+	runner := ytdlptest.New(process)
+	SetRunner(runner)
+	SetRetry(1, 0)
+	SetCache(0, 0, 0)
+	t.Cleanup(func() {
+		SetRunner(nil)
+		SetRetry(previousAttempts, previousDelay)
+		SetCache(5*time.Minute, 256, 0)
+	})
+	return runner
+}
+
+// fakeBinary puts a yt-dlp running the shell script first on the PATH
+func fakeBinary(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "yt-dlp"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("failed to write fake yt-dlp: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestGetAvailableFormatsCancelled(t *testing.T) {
+	fakeBinary(t, "exec sleep 30\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := GetAvailableFormats(ctx, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", sources.YouTube)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	// The process is killed rather than waited for
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("returned after %v", elapsed)
+	}
+}
+
+const emptyJSON = `{"id": "dQw4w9WgXcQ", "title": "Test video", "duration": 212, "formats": []}`
+
+func TestGetAvailableFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		stderr  string
+		exitErr error
+		video   []string
+		audio   []string
+		wantErr error
+	}{
+		{
+			name:   "success",
+			stdout: ytdlptest.Fixture(t, "youtube_video.json"),
+			video:  []string{"160", "134", "137"},
+			audio:  []string{"140", "251"},
+		},
+		{
+			name:   "empty formats",
+			stdout: emptyJSON,
+			video:  []string{},
+			audio:  []string{},
+		},
+		{
+			name:    "stderr error",
+			stderr:  "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable\n",
+			exitErr: errors.New("exit status 1"),
+			wantErr: ErrVideoUnavailable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeYtdlp(t, ytdlptest.Replay(test.stdout, test.stderr, test.exitErr))
+
+			media, err := GetAvailableFormats(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", sources.YouTube)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("got error %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if media.ID != "dQw4w9WgXcQ" || media.Title == "" {
+				t.Errorf("got id %q and title %q", media.ID, media.Title)
+			}
+			var video, audio []string
+			for _, format := range media.VideoFormats {
+				video = append(video, format.SourceIdentifier)
+			}
+			for _, format := range media.AudioFormats {
+				audio = append(audio, format.SourceIdentifier)
+			}
+			if !slices.Equal(video, test.video) {
+				t.Errorf("got video formats %v, want %v", video, test.video)
+			}
+			if !slices.Equal(audio, test.audio) {
+				t.Errorf("got audio formats %v, want %v", audio, test.audio)
+			}
+		})
+	}
+}
+
+func TestGetVideoFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats []Format
+		want    []string
+	}{
+		{
+			name: "success",
+			formats: []Format{
+				{FormatID: "137", Vcodec: "avc1.640028", Acodec: "none", Height: 1080},
+				{FormatID: "140", Vcodec: "none", Acodec: "mp4a.40.2"},
+				{FormatID: "18", Vcodec: "avc1.42001E", Acodec: "mp4a.40.2"},
+			},
+			want: []string{"137"},
+		},
+		{
+			name:    "empty formats",
+			formats: []Format{},
+			want:    []string{},
+		},
+		{
+			name: "unknown codecs",
+			formats: []Format{
+				{FormatID: "hls", Vcodec: "", Acodec: ""},
+			},
+			want: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formats := getVideoFormats(test.formats, sources.YouTube)
+			if formats == nil {
+				t.Fatal("got nil, want an empty slice")
+			}
+
+			var got []string
+			for _, format := range formats {
+				got = append(got, format.SourceIdentifier)
+				if format.Source != sources.YouTube {
+					t.Errorf("format %s has source %s", format.SourceIdentifier, format.Source)
+				}
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetAudioFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats []Format
+		want    []string
+	}{
+		{
+			name: "success",
+			formats: []Format{
+				{FormatID: "137", Vcodec: "avc1.640028", Acodec: "none"},
+				{FormatID: "140", Vcodec: "none", Acodec: "mp4a.40.2", Abr: 128},
+				{FormatID: "18", Vcodec: "avc1.42001E", Acodec: "mp4a.40.2"},
+			},
+			want: []string{"140"},
+		},
+		{
+			name:    "empty formats",
+			formats: []Format{},
+			want:    []string{},
+		},
+		{
+			name: "storyboards only",
+			formats: []Format{
+				{FormatID: "sb0", Ext: "mhtml", Vcodec: "none", Acodec: "none"},
+			},
+			want: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			formats := getAudioFormats(test.formats, sources.YouTube)
+			if formats == nil {
+				t.Fatal("got nil, want an empty slice")
+			}
+
+			var got []string
+			for _, format := range formats {
+				got = append(got, format.SourceIdentifier)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsVideoOnlyAndIsAudioOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		vcodec    string
+		acodec    string
+		videoOnly bool
+		audioOnly bool
+	}{
+		{name: "video only", vcodec: "avc1.640028", acodec: "none", videoOnly: true},
+		{name: "audio only", vcodec: "none", acodec: "opus", audioOnly: true},
+		{name: "combined", vcodec: "avc1.42001E", acodec: "mp4a.40.2"},
+		{name: "neither", vcodec: "none", acodec: "none"},
+		{name: "both unknown", vcodec: "", acodec: ""},
+		{name: "unknown audio", vcodec: "vp9", acodec: ""},
+		{name: "unknown video", vcodec: "", acodec: "opus"},
+		{name: "no audio, unknown video", vcodec: "", acodec: "none"},
+		{name: "no video, unknown audio", vcodec: "none", acodec: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format := Format{Vcodec: test.vcodec, Acodec: test.acodec}
+			if got := isVideoOnly(format); got != test.videoOnly {
+				t.Errorf("isVideoOnly got %v, want %v", got, test.videoOnly)
+			}
+			if got := isAudioOnly(format); got != test.audioOnly {
+				t.Errorf("isAudioOnly got %v, want %v", got, test.audioOnly)
+			}
+		})
+	}
+}
+
+func TestGetAvailableFormatsWithWarnings(t *testing.T) {
+	const warnings = "WARNING: [youtube] dQw4w9WgXcQ: nsig extraction failed: Some formats may be missing\n" +
+		"WARNING: [youtube] Unable to download webpage: HTTP Error 429: Too Many Requests\n"
+
+	tests := []struct {
+		name    string
+		stdout  string
+		exitErr error
+		wantErr bool
+	}{
+		{name: "valid output", stdout: ytdlptest.Fixture(t, "youtube_video.json")},
+		{name: "failed exit", stdout: ytdlptest.Fixture(t, "youtube_video.json"), exitErr: errors.New("exit status 1"), wantErr: true},
+		{name: "invalid output", stdout: "not json", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeYtdlp(t, ytdlptest.Replay(test.stdout, warnings, test.exitErr))
+
+			media, err := GetAvailableFormats(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", sources.YouTube)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got nil error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(media.VideoFormats) != 3 || len(media.AudioFormats) != 2 {
+				t.Errorf("got %d video and %d audio formats, want 3 and 2", len(media.VideoFormats), len(media.AudioFormats))
+			}
+		})
+	}
+}
+
+func TestGetAvailableFormatsLargeOutput(t *testing.T) {
+	// A real process, its pipes only buffer 64 KiB before the writes block
+	script := filepath.Join(t.TempDir(), "yt-dlp")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+i=0
+while [ $i -lt 2000 ]; do
+	echo "WARNING: [youtube] dQw4w9WgXcQ: a rather long warning line to fill up the stderr pipe buffer $i" >&2
+	i=$((i + 1))
+done
+printf '{"id": "dQw4w9WgXcQ", "title": "Test video", "description": "'
+head -c 1048576 /dev/zero | tr '\0' 'x'
+printf '", "formats": [{"format_id": "140", "ext": "m4a", "vcodec": "none", "acodec": "mp4a.40.2", "abr": 128}]}'
+echo "WARNING: done" >&2
+`), 0o755)
+	if err != nil {
+		t.Fatalf("failed to write fake yt-dlp: %v", err)
+	}
+
+	previousPath, previousAttempts, previousDelay := binaryPath, retryAttempts, retryBaseDelay
+	SetBinaryPath(script)
+	SetRetry(1, 0)
+	SetCache(0, 0, 0)
+	t.Cleanup(func() {
+		SetBinaryPath(previousPath)
+		SetRetry(previousAttempts, previousDelay)
+		SetCache(5*time.Minute, 256, 0)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	media, err := GetAvailableFormats(ctx, "https://www.youtube.com/watch?v=dQw4w9WgXcQ", sources.YouTube)
+	if err != nil {
+		t.Fatalf("unexpected error, deadlocked if cancelled: %v", err)
+	}
+	if len(media.Description) != 1048576 {
+		t.Errorf("got a description of %d bytes, want 1048576", len(media.Description))
+	}
+	if len(media.AudioFormats) != 1 {
+		t.Errorf("got %d audio formats, want 1", len(media.AudioFormats))
+	}
+}
+
+func TestGetStoryboards(t *testing.T) {
+	formats := []Format{
+		{FormatID: "sb0", Ext: "mhtml", FormatNote: "storyboard", Vcodec: "none", Acodec: "none", Width: 160, Height: 90, Rows: 5, Columns: 5, Fps: 0.5},
+		{FormatID: "sb1", Ext: "mhtml", Vcodec: "none", Acodec: "none", Width: 80, Height: 45, Rows: 10, Columns: 10, Fps: 0.2},
+		{FormatID: "137", Ext: "mp4", Vcodec: "avc1.640028", Acodec: "none", Width: 1920, Height: 1080},
+		{FormatID: "140", Ext: "m4a", Vcodec: "none", Acodec: "mp4a.40.2"},
+	}
+
+	storyboards := getStoryboards(formats, sources.YouTube)
+	if len(storyboards) != 2 {
+		t.Fatalf("got %d storyboards, want 2", len(storyboards))
+	}
+
+	first := storyboards[0]
+	if first.SourceIdentifier != "sb0" || first.Width != 160 || first.Height != 90 || first.Rows != 5 || first.Columns != 5 || first.FPS != 0.5 {
+		t.Errorf("got %+v, want sb0 as a 5 by 5 grid of 160x90 at 0.5 fps", first)
+	}
+	if storyboards[1].SourceIdentifier != "sb1" {
+		t.Errorf("got %q second, want sb1", storyboards[1].SourceIdentifier)
+	}
+}
+
+func TestRawJSON(t *testing.T) {
+	const output = `{"id": "dQw4w9WgXcQ", "_internal": {"player_url": "/s/player/base.js"}}`
+	fakeYtdlp(t, ytdlptest.Replay(output+"\n", "", nil))
+
+	raw, err := RawJSON(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Passed through byte for byte, including fields MediaInfo doesn't know
+	if string(raw) != output {
+		t.Errorf("got %s, want %s", raw, output)
+	}
+}
+
+func TestGetVideoFormatsRedditBitrate(t *testing.T) {
+	formats := []Format{
+		{FormatID: "dash-720", Vcodec: "avc1.4d401f", Acodec: "none", Height: 720, Tbr: 2400},
+		{FormatID: "dash-480", Vcodec: "avc1.4d401e", Acodec: "none", Height: 480, Vbr: 1200, Tbr: 1300},
+	}
+
+	tests := []struct {
+		source sources.Source
+		want   []float64
+	}{
+		{sources.Reddit, []float64{2400, 1200}},
+		{sources.YouTube, []float64{0, 1200}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source.String(), func(t *testing.T) {
+			var got []float64
+			for _, format := range getVideoFormats(formats, test.source) {
+				got = append(got, format.VideoBitrate)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got bitrates %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWorkingFormats(t *testing.T) {
+	fakeYtdlp(t, ytdlptest.Replay(`{
+		"id": "dQw4w9WgXcQ",
+		"title": "Test video",
+		"formats": [
+			{"format_id": "137", "ext": "mp4", "vcodec": "avc1.640028", "acodec": "none", "width": 1920, "height": 1080, "vbr": 4000, "__working": true},
+			{"format_id": "136", "ext": "mp4", "vcodec": "avc1.4d401f", "acodec": "none", "width": 1280, "height": 720, "vbr": 2000, "__working": false},
+			{"format_id": "135", "ext": "mp4", "vcodec": "avc1.4d401e", "acodec": "none", "width": 854, "height": 480, "vbr": 1000},
+			{"format_id": "140", "ext": "m4a", "vcodec": "none", "acodec": "mp4a.40.2", "abr": 128, "__working": true},
+			{"format_id": "251", "ext": "webm", "vcodec": "none", "acodec": "opus", "abr": 160, "__working": false},
+			{"format_id": "250", "ext": "webm", "vcodec": "none", "acodec": "opus", "abr": 64}
+		]
+	}`, "", nil))
+
+	media, err := GetAvailableFormats(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", sources.YouTube)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var video, audio []string
+	for _, format := range media.VideoFormats {
+		video = append(video, format.SourceIdentifier)
+	}
+	for _, format := range media.AudioFormats {
+		audio = append(audio, format.SourceIdentifier)
+	}
+	// Working and unchecked formats are kept, only broken ones are left out
+	if want := []string{"137", "135"}; !slices.Equal(video, want) {
+		t.Errorf("got video formats %v, want %v", video, want)
+	}
+	if want := []string{"140", "250"}; !slices.Equal(audio, want) {
+		t.Errorf("got audio formats %v, want %v", audio, want)
+	}
+	if media.Stats().NonWorking != 2 {
+		t.Errorf("got %d non-working formats, want 2", media.Stats().NonWorking)
+	}
+}
+
+func TestFilterStats(t *testing.T) {
+	fakeYtdlp(t, ytdlptest.Replay(`{
+		"id": "dQw4w9WgXcQ",
+		"title": "Test video",
+		"formats": [
+			{"format_id": "137", "ext": "mp4", "vcodec": "avc1.640028", "acodec": "none", "width": 1920, "height": 1080, "vbr": 4000},
+			{"format_id": "137-low", "ext": "mp4", "vcodec": "avc1.640028", "acodec": "none", "width": 1920, "height": 1080, "vbr": 2000},
+			{"format_id": "136", "ext": "mp4", "vcodec": "avc1.4d401f", "acodec": "none", "width": 1280, "height": 720, "vbr": 2000, "__working": false},
+			{"format_id": "135", "ext": "mp4", "vcodec": "avc1.4d401e", "acodec": "none", "width": 854, "height": 480},
+			{"format_id": "134", "ext": "mp4", "vcodec": "avc1.4d4015", "acodec": "none", "width": 640, "height": 360, "vbr": 500, "has_drm": true},
+			{"format_id": "140", "ext": "m4a", "vcodec": "none", "acodec": "mp4a.40.2", "abr": 128},
+			{"format_id": "140-drc", "ext": "m4a", "vcodec": "none", "acodec": "mp4a.40.2", "abr": 96},
+			{"format_id": "139", "ext": "webm", "vcodec": "none", "acodec": "opus", "abr": 0, "__working": false}
+		]
+	}`, "", nil))
+
+	media, err := GetAvailableFormats(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", sources.YouTube)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	media.CleanFormats()
+
+	want := info.FilterStats{ZeroBitrate: 1, Duplicate: 2, NonWorking: 2, DRM: 1}
+	if got := *media.Stats(); got != want {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+	if len(media.VideoFormats) != 1 || len(media.AudioFormats) != 1 {
+		t.Errorf("got %d video and %d audio formats, want 1 of each", len(media.VideoFormats), len(media.AudioFormats))
+	}
+}
+
+func TestWithoutFormatCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantCheck bool
+	}{
+		{name: "checked by default", wantCheck: true},
+		{name: "without format check", opts: []Option{WithoutFormatCheck()}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runner := fakeYtdlp(t, ytdlptest.Replay(ytdlptest.Fixture(t, "youtube_video.json"), "", nil))
+
+			_, err := GetAvailableFormats(context.Background(), "https://www.youtube.com/watch?v=dQw4w9WgXcQ", sources.YouTube, test.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gotArgs := runner.Calls()[0]
+			if got := slices.Contains(gotArgs, "--check-all-formats"); got != test.wantCheck {
+				t.Errorf("got --check-all-formats %v, want %v in %q", got, test.wantCheck, gotArgs)
+			}
+		})
+	}
+}
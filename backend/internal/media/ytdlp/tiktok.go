@@ -0,0 +1,13 @@
+package ytdlp
+
+import "strings"
+
+// isWatermarked reports whether a TikTok format is the "download" variant
+// with the TikTok logo burned in, as opposed to the clean playback stream
+func isWatermarked(format Format) bool {
+	note := strings.ToLower(format.FormatNote)
+	if strings.Contains(note, "no watermark") || strings.Contains(note, "without watermark") {
+		return false
+	}
+	return strings.Contains(note, "watermark") || strings.HasPrefix(format.FormatID, "download")
+}
@@ -0,0 +1,24 @@
+package ytdlp
+
+import "testing"
+
+func TestIsWatermarked(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		want   bool
+	}{
+		{name: "download variant", format: Format{FormatID: "download_addr-0"}, want: true},
+		{name: "noted watermark", format: Format{FormatID: "h264_540p", FormatNote: "Watermarked"}, want: true},
+		{name: "noted without watermark", format: Format{FormatID: "download", FormatNote: "Direct video (API), no watermark"}, want: false},
+		{name: "playback stream", format: Format{FormatID: "bytevc1_1080p_1"}, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isWatermarked(test.format); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,42 @@ type MediaInfo struct {
 	Formats     []Format `json:"formats"`
 	Duration    float64  `json:"duration"`
 	OriginalURL string   `json:"original_url"`
+	IsLive      bool     `json:"is_live,omitempty"`
+	LiveStatus  string   `json:"live_status,omitempty"`
+
+	Uploader    string `json:"uploader,omitempty"`
+	UploaderURL string `json:"uploader_url,omitempty"`
+	UploadDate  string `json:"upload_date,omitempty"`
+	ViewCount   int64  `json:"view_count,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Thumbnail  string      `json:"thumbnail,omitempty"`
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+
+	Chapters []Chapter `json:"chapters,omitempty"`
+
+	Subtitles         map[string][]SubtitleFormat `json:"subtitles,omitempty"`
+	AutomaticCaptions map[string][]SubtitleFormat `json:"automatic_captions,omitempty"`
+}
+
+type Thumbnail struct {
+	ID         string `json:"id"`
+	URL        string `json:"url"`
+	Width      int64  `json:"width,omitempty"`
+	Height     int64  `json:"height,omitempty"`
+	Preference int64  `json:"preference,omitempty"`
+}
+
+type Chapter struct {
+	Title     string  `json:"title"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
+
+type SubtitleFormat struct {
+	Ext  string `json:"ext"`
+	URL  string `json:"url"`
+	Name string `json:"name,omitempty"`
 }
 
 type Format struct {
@@ -25,11 +61,12 @@ type Format struct {
 	VideoExt           string  `json:"video_ext"`
 	Vbr                float64 `json:"vbr"`
 	Abr                float64 `json:"abr"`
+	Tbr                float64 `json:"tbr,omitempty"`
 	Resolution         string  `json:"resolution"`
 	AspectRatio        float64 `json:"aspect_ratio"`
 	FilesizeApprox     int64   `json:"filesize_approx,omitempty"`
 	Format             string  `json:"format"`
-	Working            bool    `json:"__working"`
+	Working            *bool   `json:"__working,omitempty"`
 	ManifestURL        string  `json:"manifest_url,omitempty"`
 	Language           string  `json:"language,omitempty"`
 	Quality            float64 `json:"quality,omitempty"`
@@ -40,4 +77,21 @@ type Format struct {
 	AudioChannels      int64   `json:"audio_channels,omitempty"`
 	LanguagePreference int64   `json:"language_preference,omitempty"`
 	Container          string  `json:"container,omitempty"`
+
+	HTTPHeaders map[string]string `json:"http_headers,omitempty"`
+}
+
+type PlaylistEntry struct {
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	URL      string  `json:"url"`
+	WebURL   string  `json:"webpage_url,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+func (e PlaylistEntry) entryURL() string {
+	if e.WebURL != "" {
+		return e.WebURL
+	}
+	return e.URL
 }
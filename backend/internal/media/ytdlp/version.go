@@ -0,0 +1,110 @@
+package ytdlp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"media-downloader/internal/metrics"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// How long the outcome of a version check is reused, so frequent
+	// readiness probes don't each start yt-dlp
+	versionCheckTTL = 10 * time.Second
+
+	versionTimeout = 10 * time.Second
+)
+
+// The version detected by Version, empty until it succeeded once
+var detected struct {
+	mu      sync.Mutex
+	version string
+}
+
+// Version returns the version of yt-dlp, such as "2024.08.06". It's only
+// detected once as the binary doesn't change while the server runs.
+func Version(ctx context.Context) (string, error) {
+	detected.mu.Lock()
+	defer detected.mu.Unlock()
+
+	if detected.version != "" {
+		return detected.version, nil
+	}
+
+	version, err := runVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	detected.version = version
+	metrics.YtdlpInfo.WithLabelValues(version).Set(1)
+	return version, nil
+}
+
+var versionCheck struct {
+	mu      sync.Mutex
+	version string
+	err     error
+	checked time.Time
+}
+
+// CheckVersion runs yt-dlp --version to make sure it still works, returning
+// the version it reports. Unlike Version it runs again once the recent
+// outcome it reuses is outdated.
+func CheckVersion(ctx context.Context) (string, error) {
+	versionCheck.mu.Lock()
+	defer versionCheck.mu.Unlock()
+
+	if !versionCheck.checked.IsZero() && time.Since(versionCheck.checked) < versionCheckTTL {
+		return versionCheck.version, versionCheck.err
+	}
+
+	version, err := runVersion(ctx)
+	// A caller giving up says nothing about yt-dlp
+	if ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+		versionCheck.version, versionCheck.err, versionCheck.checked = version, err, time.Now()
+	}
+	return version, err
+}
+
+func runVersion(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+
+	// Bypasses the process slots, a busy server is still a ready one
+	stdout, stderr, wait, err := runner.Run(ctx, binaryPath, "--version")
+	if err != nil {
+		return "", fmt.Errorf("failed to run yt-dlp: %w", err)
+	}
+
+	var stderrBuffer bytes.Buffer
+	stderrDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&stderrBuffer, stderr)
+		close(stderrDone)
+	}()
+
+	output, _ := io.ReadAll(io.LimitReader(stdout, 1024))
+	_, _ = io.Copy(io.Discard, stdout)
+	<-stderrDone
+
+	if err = wait(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", fmt.Errorf("yt-dlp cancelled: %w", ctxErr)
+		}
+		if stderrBuffer.Len() > 0 {
+			return "", fmt.Errorf("yt-dlp failed: %s", strings.TrimSpace(stderrBuffer.String()))
+		}
+		return "", fmt.Errorf("yt-dlp failed: %w", err)
+	}
+
+	version := strings.TrimSpace(string(output))
+	if version == "" {
+		return "", errors.New("yt-dlp reported no version")
+	}
+	return version, nil
+}
@@ -0,0 +1,66 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"media-downloader/internal/media/ytdlp/ytdlptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		stdout      string
+		stderr      string
+		exitErr     error
+		wantVersion string
+		wantErr     string
+	}{
+		{name: "working", stdout: "2024.08.06\n", wantVersion: "2024.08.06"},
+		{name: "broken", stderr: "ImportError: No module named yt_dlp\n", exitErr: errors.New("exit status 1"), wantErr: "No module named yt_dlp"},
+		{name: "no version", stdout: "\n", wantErr: "no version"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeYtdlp(t, ytdlptest.Replay(test.stdout, test.stderr, test.exitErr))
+			// Every case needs a fresh check
+			versionCheck.checked = time.Time{}
+
+			version, err := CheckVersion(context.Background())
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want one mentioning %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version != test.wantVersion {
+				t.Errorf("got version %q, want %q", version, test.wantVersion)
+			}
+		})
+	}
+}
+
+func TestVersionDetectedOnce(t *testing.T) {
+	runner := fakeYtdlp(t, ytdlptest.Replay("2024.08.06\n", "", nil))
+	detected.version = ""
+	t.Cleanup(func() { detected.version = "" })
+
+	for range 2 {
+		version, err := Version(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if version != "2024.08.06" {
+			t.Errorf("got version %q, want 2024.08.06", version)
+		}
+	}
+	if runs := len(runner.Calls()); runs != 1 {
+		t.Errorf("yt-dlp ran %d times, want once", runs)
+	}
+}
@@ -0,0 +1,110 @@
+// Package ytdlptest stands in for yt-dlp in tests, replaying recorded output
+// instead of running the binary. A Runner is installed with ytdlp.SetRunner.
+//
+// The fixtures in testdata mirror yt-dlp's output for public YouTube media,
+// trimmed to a handful of formats. To refresh one against the current yt-dlp,
+// run for instance
+//
+//	yt-dlp -J 'https://www.youtube.com/watch?v=dQw4w9WgXcQ' > testdata/youtube_video.json
+package ytdlptest
+
+import (
+	"context"
+	"embed"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+)
+
+//go:embed testdata
+var testdata embed.FS
+
+// Process runs in place of a yt-dlp process, writing its output to stdout and
+// stderr. The returned error is what waiting for the process returns.
+type Process func(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error
+
+// Runner runs a Process in place of yt-dlp with the write ends of real pipes,
+// so like a real process it blocks once a pipe's buffer is full of output
+// nobody reads. It implements ytdlp.Runner and remembers the arguments of
+// every run.
+type Runner struct {
+	process Process
+
+	mu    sync.Mutex
+	calls [][]string
+}
+
+// New returns a Runner running process for every yt-dlp run
+func New(process Process) *Runner {
+	return &Runner{process: process}
+}
+
+func (r *Runner) Run(ctx context.Context, bin string, args ...string) (io.ReadCloser, io.ReadCloser, func() error, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, slices.Clone(args))
+	r.mu.Unlock()
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		_ = stdoutReader.Close()
+		_ = stdoutWriter.Close()
+		return nil, nil, nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := r.process(ctx, args, stdoutWriter, stderrWriter)
+		_ = stdoutWriter.Close()
+		_ = stderrWriter.Close()
+		done <- err
+	}()
+
+	return stdoutReader, stderrReader, func() error { return <-done }, nil
+}
+
+// Calls returns the arguments of every run so far, oldest first
+func (r *Runner) Calls() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
+
+// Replay returns a process printing stderr, then stdout, and exiting with exitErr
+func Replay(stdout string, stderr string, exitErr error) Process {
+	return func(ctx context.Context, args []string, stdoutWriter io.Writer, stderrWriter io.Writer) error {
+		if _, err := io.WriteString(stderrWriter, stderr); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(stdoutWriter, stdout); err != nil {
+			return err
+		}
+		return exitErr
+	}
+}
+
+// WithArg runs matched for runs passing arg, such as "--output" for downloads,
+// and otherwise for the rest
+func WithArg(arg string, matched Process, otherwise Process) Process {
+	return func(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+		if slices.Contains(args, arg) {
+			return matched(ctx, args, stdout, stderr)
+		}
+		return otherwise(ctx, args, stdout, stderr)
+	}
+}
+
+// Fixture returns the recorded output in testdata/name
+func Fixture(t testing.TB, name string) string {
+	t.Helper()
+	data, err := testdata.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return string(data)
+}
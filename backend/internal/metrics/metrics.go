@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"media-downloader/internal/cache"
+	"net/http"
+)
+
+var (
+	// Requests to the quality and download endpoints, by endpoint and status
+	Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "media_downloader_requests_total",
+		Help: "Requests to the quality and download endpoints.",
+	}, []string{"endpoint", "status"})
+
+	// Error responses by their error code
+	Errors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "media_downloader_errors_total",
+		Help: "Error responses by error code.",
+	}, []string{"code"})
+
+	YtdlpInvocations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "media_downloader_ytdlp_invocations_total",
+		Help: "yt-dlp processes started.",
+	})
+
+	YtdlpDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "media_downloader_ytdlp_duration_seconds",
+		Help:    "Run time of yt-dlp processes, from start until they exit.",
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300, 600},
+	})
+
+	// Always 1, labelled with the yt-dlp version once it's known
+	YtdlpInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "media_downloader_ytdlp_info",
+		Help: "The version of yt-dlp in use.",
+	}, []string{"version"})
+)
+
+// RegisterCache exports the statistics of a cache, read whenever the metrics
+// are collected
+func RegisterCache(name string, stats func() cache.Stats) {
+	labels := prometheus.Labels{"cache": name}
+	counter := func(metric string, help string, value func(cache.Stats) uint64) {
+		promauto.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "media_downloader_cache_" + metric + "_total",
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 {
+			return float64(value(stats()))
+		})
+	}
+
+	counter("hits", "Cache lookups that found an entry.", func(s cache.Stats) uint64 { return s.Hits })
+	counter("misses", "Cache lookups that found nothing.", func(s cache.Stats) uint64 { return s.Misses })
+	counter("evictions", "Entries dropped to make room.", func(s cache.Stats) uint64 { return s.Evictions })
+	counter("expirations", "Entries dropped for being too old.", func(s cache.Stats) uint64 { return s.Expirations })
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "media_downloader_cache_entries",
+		Help:        "Entries currently in the cache.",
+		ConstLabels: labels,
+	}, func() float64 {
+		return float64(stats().Size)
+	})
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"io"
+	"media-downloader/internal/cache"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegisterCache(t *testing.T) {
+	stats := cache.Stats{Hits: 3, Misses: 2, Evictions: 1, Expirations: 4, Size: 5}
+	RegisterCache("test", func() cache.Stats { return stats })
+	stats.Hits = 7
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Result().Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+
+	// The statistics are read when collected, not when registered
+	for _, want := range []string{
+		`media_downloader_cache_hits_total{cache="test"} 7`,
+		`media_downloader_cache_misses_total{cache="test"} 2`,
+		`media_downloader_cache_evictions_total{cache="test"} 1`,
+		`media_downloader_cache_expirations_total{cache="test"} 4`,
+		`media_downloader_cache_entries{cache="test"} 5`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics are missing %q", want)
+		}
+	}
+}
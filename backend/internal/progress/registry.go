@@ -0,0 +1,62 @@
+package progress
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Trackers untouched for this long are dropped, whether finished or abandoned
+const idleTimeout = 10 * time.Minute
+
+// Registry hands out trackers by a random ID it generates, so a progress
+// subscriber and the download itself can find each other regardless of who
+// comes first, while other clients can't guess the ID to follow along
+type Registry struct {
+	mu       sync.Mutex
+	trackers map[string]*Tracker
+}
+
+func NewRegistry() *Registry {
+	return &Registry{trackers: make(map[string]*Tracker)}
+}
+
+// Create registers a new tracker under a fresh ID
+func (r *Registry) Create() (string, *Tracker, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(bytes)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune()
+
+	tracker := NewTracker()
+	r.trackers[id] = tracker
+	return id, tracker, nil
+}
+
+// Get returns the tracker created under id, false if there is none or it
+// has been dropped
+func (r *Registry) Get(id string) (*Tracker, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune()
+
+	tracker, ok := r.trackers[id]
+	return tracker, ok
+}
+
+func (r *Registry) prune() {
+	cutoff := time.Now().Add(-idleTimeout)
+	for id, tracker := range r.trackers {
+		if tracker.idleSince().Before(cutoff) {
+			delete(r.trackers, id)
+		}
+	}
+}
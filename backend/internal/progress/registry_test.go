@@ -0,0 +1,48 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistryCreateAndGet(t *testing.T) {
+	registry := NewRegistry()
+	firstID, first, err := registry.Create()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secondID, second, err := registry.Create()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if firstID == secondID || first == second {
+		t.Errorf("got the same ID %q or tracker twice", firstID)
+	}
+	if got, ok := registry.Get(firstID); !ok || got != first {
+		t.Error("didn't get the tracker created under its ID")
+	}
+	if _, ok := registry.Get("guessed"); ok {
+		t.Error("got a tracker for an ID that was never created")
+	}
+}
+
+func TestRegistryPrunesIdleTrackers(t *testing.T) {
+	registry := NewRegistry()
+	idleID, idle, err := registry.Create()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	idle.updated = time.Now().Add(-idleTimeout - time.Second)
+	activeID, active, err := registry.Create()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := registry.Get(idleID); ok {
+		t.Error("got the idle tracker back, want it dropped")
+	}
+	if got, ok := registry.Get(activeID); !ok || got != active {
+		t.Error("the active tracker was dropped")
+	}
+}
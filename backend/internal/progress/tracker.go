@@ -0,0 +1,122 @@
+package progress
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type Snapshot struct {
+	Downloaded uint64  `json:"downloaded"`
+	Total      uint64  `json:"total"`
+	Percent    float64 `json:"percent"`
+	Done       bool    `json:"done"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Tracker follows the progress of a single download, safe for concurrent use
+type Tracker struct {
+	mu         sync.Mutex
+	downloaded uint64
+	total      uint64
+	done       bool
+	err        error
+	updated    time.Time
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{updated: time.Now()}
+}
+
+func (t *Tracker) Add(n int) {
+	if n <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.downloaded += uint64(n)
+	t.updated = time.Now()
+}
+
+// SetTotal records the expected size, ignoring unknown (zero) sizes
+func (t *Tracker) SetTotal(total uint64) {
+	if total == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = total
+	t.updated = time.Now()
+}
+
+// Finish marks the download as done, only the first call has any effect
+func (t *Tracker) Finish(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return
+	}
+	t.done = true
+	t.err = err
+	t.updated = time.Now()
+}
+
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := Snapshot{
+		Downloaded: t.downloaded,
+		Total:      t.total,
+		Done:       t.done,
+	}
+	if t.total > 0 {
+		snapshot.Percent = min(100, float64(t.downloaded)/float64(t.total)*100)
+	}
+	if t.err != nil {
+		snapshot.Error = t.err.Error()
+	}
+
+	return snapshot
+}
+
+func (t *Tracker) idleSince() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.updated
+}
+
+type trackerKey struct{}
+
+// WithTracker attaches a tracker to ctx so the download path can report the
+// total size as soon as the source knows it
+func WithTracker(ctx context.Context, tracker *Tracker) context.Context {
+	if tracker == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, trackerKey{}, tracker)
+}
+
+func From(ctx context.Context) (*Tracker, bool) {
+	tracker, ok := ctx.Value(trackerKey{}).(*Tracker)
+	return tracker, ok
+}
+
+// Reader counts every byte read through it towards a tracker
+type Reader struct {
+	io.ReadCloser
+	tracker *Tracker
+}
+
+func NewReader(reader io.ReadCloser, tracker *Tracker) *Reader {
+	return &Reader{ReadCloser: reader, tracker: tracker}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.tracker.Add(n)
+	return n, err
+}
@@ -0,0 +1,71 @@
+package progress
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTrackerSnapshot(t *testing.T) {
+	tracker := NewTracker()
+	if got := tracker.Snapshot(); got != (Snapshot{}) {
+		t.Errorf("got %+v for a new tracker, want nothing downloaded", got)
+	}
+
+	// Unknown sizes and empty reads don't count
+	tracker.SetTotal(0)
+	tracker.Add(0)
+	tracker.Add(-1)
+	tracker.Add(250)
+	if got, want := tracker.Snapshot(), (Snapshot{Downloaded: 250}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	tracker.SetTotal(1000)
+	if got, want := tracker.Snapshot(), (Snapshot{Downloaded: 250, Total: 1000, Percent: 25}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Estimated totals can be exceeded, the percentage can't
+	tracker.Add(1000)
+	if got := tracker.Snapshot().Percent; got != 100 {
+		t.Errorf("got %v percent, want 100", got)
+	}
+}
+
+func TestTrackerFinish(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Finish(errors.New("connection reset"))
+	tracker.Finish(nil)
+
+	snapshot := tracker.Snapshot()
+	if !snapshot.Done || snapshot.Error != "connection reset" {
+		t.Errorf("got %+v, want the first outcome to stick", snapshot)
+	}
+}
+
+func TestReader(t *testing.T) {
+	tracker := NewTracker()
+	reader := NewReader(io.NopCloser(strings.NewReader("0123456789")), tracker)
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := tracker.Snapshot().Downloaded; got != 10 {
+		t.Errorf("got %d bytes downloaded, want 10", got)
+	}
+}
+
+func TestWithTracker(t *testing.T) {
+	if _, ok := From(WithTracker(context.Background(), nil)); ok {
+		t.Error("got a tracker from a context without one")
+	}
+
+	tracker := NewTracker()
+	got, ok := From(WithTracker(context.Background(), tracker))
+	if !ok || got != tracker {
+		t.Errorf("got %p, want %p", got, tracker)
+	}
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket measured in bytes, safe to share between goroutines.
+// A nil *Bucket never limits.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewBucket(bytesPerSecond int64) *Bucket {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+
+	rate := float64(bytesPerSecond)
+	return &Bucket{
+		rate:   rate,
+		burst:  rate,
+		tokens: rate,
+		last:   time.Now(),
+	}
+}
+
+// Idle reports whether the bucket has refilled completely, at which point it
+// behaves exactly like a new one
+func (b *Bucket) Idle() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens+time.Since(b.last).Seconds()*b.rate >= b.burst
+}
+
+// Take removes n tokens if they are available right away, otherwise it takes
+// nothing and returns how long until they would be
+func (b *Bucket) Take(n int) time.Duration {
+	if b == nil || n <= 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens < float64(n) {
+		return time.Duration((float64(n) - b.tokens) / b.rate * float64(time.Second))
+	}
+	b.tokens -= float64(n)
+	return 0
+}
+
+// Burst returns the largest amount that can be taken in a single Wait call
+func (b *Bucket) Burst() int {
+	if b == nil {
+		return 0
+	}
+	return int(b.burst)
+}
+
+// Wait blocks until n bytes are available in the bucket or ctx is done
+func (b *Bucket) Wait(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	// Reserve the tokens now, possibly going into debt, so concurrent
+	// readers queue up behind each other instead of racing for refills
+	b.tokens -= float64(n)
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give back the reservation
+		b.mu.Lock()
+		b.tokens = min(b.burst, b.tokens+float64(n))
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// How often buckets that have refilled completely are dropped
+const pruneInterval = time.Minute
+
+// Keyed hands out a separate token bucket per key, such as a client IP,
+// safe to share between goroutines. A nil *Keyed never limits.
+type Keyed struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*Bucket
+	lastPrune time.Time
+}
+
+// NewKeyed allows each key perMinute requests on average and up to burst at
+// once, it returns nil when perMinute isn't positive
+func NewKeyed(perMinute int64, burst int64) *Keyed {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &Keyed{
+		rate:      float64(perMinute) / 60,
+		burst:     float64(burst),
+		buckets:   make(map[string]*Bucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// Allow takes a token for key, returning zero if it was available and
+// otherwise how long the caller has to wait for one
+func (k *Keyed) Allow(key string) time.Duration {
+	if k == nil {
+		return 0
+	}
+
+	return k.bucket(key).Take(1)
+}
+
+func (k *Keyed) bucket(key string) *Bucket {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if time.Since(k.lastPrune) >= pruneInterval {
+		k.prune()
+	}
+
+	bucket, ok := k.buckets[key]
+	if !ok {
+		bucket = &Bucket{
+			rate:   k.rate,
+			burst:  k.burst,
+			tokens: k.burst,
+			last:   time.Now(),
+		}
+		k.buckets[key] = bucket
+	}
+	return bucket
+}
+
+func (k *Keyed) prune() {
+	for key, bucket := range k.buckets {
+		if bucket.Idle() {
+			delete(k.buckets, key)
+		}
+	}
+	k.lastPrune = time.Now()
+}
@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyed(t *testing.T) {
+	limiter := NewKeyed(60, 2)
+
+	for i := range 2 {
+		if wait := limiter.Allow("client"); wait != 0 {
+			t.Fatalf("request %d within the burst had to wait %v", i, wait)
+		}
+	}
+
+	wait := limiter.Allow("client")
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("got wait %v after the burst, want at most a second", wait)
+	}
+
+	// Every key has a bucket of its own
+	if wait := limiter.Allow("other"); wait != 0 {
+		t.Errorf("another client had to wait %v", wait)
+	}
+}
+
+func TestKeyedDisabled(t *testing.T) {
+	limiter := NewKeyed(0, 10)
+	if limiter != nil {
+		t.Fatal("got a limiter without a rate")
+	}
+
+	// A nil limiter lets everything through
+	for range 100 {
+		if wait := limiter.Allow("client"); wait != 0 {
+			t.Fatalf("got wait %v from a disabled limiter", wait)
+		}
+	}
+}
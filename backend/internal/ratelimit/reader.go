@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+)
+
+// Reader draws from every given bucket for the bytes it reads, so a shared
+// global bucket can be layered with a per-connection one
+type Reader struct {
+	ctx     context.Context
+	reader  io.Reader
+	buckets []*Bucket
+	chunk   int
+}
+
+func NewReader(ctx context.Context, reader io.Reader, buckets ...*Bucket) *Reader {
+	var active []*Bucket
+	chunk := 0
+	for _, bucket := range buckets {
+		if bucket == nil {
+			continue
+		}
+
+		active = append(active, bucket)
+		if chunk == 0 || bucket.Burst() < chunk {
+			chunk = bucket.Burst()
+		}
+	}
+
+	return &Reader{
+		ctx:     ctx,
+		reader:  reader,
+		buckets: active,
+		chunk:   chunk,
+	}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(r.buckets) == 0 {
+		return r.reader.Read(p)
+	}
+
+	// Never read more than a single bucket can hand out at once
+	if len(p) > r.chunk {
+		p = p[:r.chunk]
+	}
+
+	n, err := r.reader.Read(p)
+	for _, bucket := range r.buckets {
+		if waitErr := bucket.Wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
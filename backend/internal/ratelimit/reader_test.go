@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReaderSharesBucketBetweenDownloads(t *testing.T) {
+	const rate = 100_000
+	bucket := NewBucket(rate)
+
+	// Each download alone fits in the initial burst, together they take
+	// (2*75_000 - 100_000) / 100_000 = 0.5s if they share the budget
+	const size = 75_000
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader := NewReader(context.Background(), bytes.NewReader(make([]byte, size)), bucket)
+			n, err := io.Copy(io.Discard, reader)
+			if err != nil || n != size {
+				t.Errorf("copied %d bytes with error %v, want %d", n, err, size)
+			}
+		}()
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("downloads took %v, the shared limit allows no less than 500ms", elapsed)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("downloads took %v, far more than the shared limit needs", elapsed)
+	}
+}
+
+func TestReaderWithoutBucketsDoesNotLimit(t *testing.T) {
+	reader := NewReader(context.Background(), bytes.NewReader(make([]byte, 1<<20)), nil)
+
+	start := time.Now()
+	if n, err := io.Copy(io.Discard, reader); err != nil || n != 1<<20 {
+		t.Fatalf("copied %d bytes with error %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("unlimited copy took %v", elapsed)
+	}
+}
+
+func TestReaderStopsWhenContextIsDone(t *testing.T) {
+	bucket := NewBucket(1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	reader := NewReader(ctx, bytes.NewReader(make([]byte, 10_000)), bucket)
+	if _, err := io.Copy(io.Discard, reader); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,11 @@
+package set
+
+// Map is a function rather than a method since methods can't introduce type
+// parameters. Elements mapped to the same value collapse into one.
+func Map[T, U comparable](s Set[T], f func(T) U) Set[U] {
+	mapped := make(Set[U], len(s))
+	for v := range s {
+		mapped.Add(f(v))
+	}
+	return mapped
+}
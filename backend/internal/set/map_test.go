@@ -0,0 +1,48 @@
+package set
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	s := of("mp4", "m4a", "webm", "mp3")
+
+	filtered := s.Filter(func(ext string) bool { return strings.HasPrefix(ext, "m") })
+	if got, want := ToSortedSlice(filtered), []string{"m4a", "mp3", "mp4"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if s.Len() != 4 {
+		t.Errorf("filtering changed the set to %v", s.ToSlice())
+	}
+
+	if got := s.Filter(func(string) bool { return false }); got == nil || got.Len() != 0 {
+		t.Errorf("got %v, want an empty set", got)
+	}
+	if got := New[string]().Filter(func(string) bool { return true }); got.Len() != 0 {
+		t.Errorf("got %v, want an empty set", got)
+	}
+}
+
+func TestMap(t *testing.T) {
+	codecs := of("avc1.640028", "avc1.4d401f", "vp09.00.40.08", "av01.0.08M.08")
+
+	families := Map(codecs, func(codec string) string {
+		family, _, _ := strings.Cut(codec, ".")
+		return family
+	})
+	// Both avc1 codecs collapse into one element
+	if got, want := ToSortedSlice(families), []string{"av01", "avc1", "vp09"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	lengths := Map(of("mp4", "webm", "m4a"), func(ext string) int { return len(ext) })
+	if got, want := ToSortedSlice(lengths), []int{3, 4}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := Map(New[string](), strings.ToUpper); got == nil || got.Len() != 0 {
+		t.Errorf("got %v, want an empty set", got)
+	}
+}
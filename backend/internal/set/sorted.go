@@ -0,0 +1,14 @@
+package set
+
+import (
+	"cmp"
+	"slices"
+)
+
+// ToSortedSlice returns the elements in ascending order. Like Map it is a
+// function because a method can't narrow the element type to cmp.Ordered.
+func ToSortedSlice[T cmp.Ordered](s Set[T]) []T {
+	slice := s.ToSlice()
+	slices.Sort(slice)
+	return slice
+}
@@ -35,8 +35,10 @@ func (s Set[T]) Len() int {
 	return len(s)
 }
 
+// ToSlice returns the elements in no particular order, see ToSortedSlice
 func (s Set[T]) ToSlice() []T {
-	var slice []T
+	// Never nil, so an empty set still marshals as an empty JSON array
+	slice := make([]T, 0, len(s))
 	for v := range s {
 		slice = append(slice, v)
 	}
@@ -52,3 +54,58 @@ func (s Set[T]) RemoveAll(vs ...T) {
 		s.Remove(v)
 	}
 }
+
+func (s Set[T]) Union(other Set[T]) Set[T] {
+	union := New[T]()
+	for v := range s {
+		union.Add(v)
+	}
+	for v := range other {
+		union.Add(v)
+	}
+	return union
+}
+
+func (s Set[T]) Intersection(other Set[T]) Set[T] {
+	intersection := New[T]()
+	for v := range s {
+		if other.Contains(v) {
+			intersection.Add(v)
+		}
+	}
+	return intersection
+}
+
+func (s Set[T]) Difference(other Set[T]) Set[T] {
+	difference := New[T]()
+	for v := range s {
+		if !other.Contains(v) {
+			difference.Add(v)
+		}
+	}
+	return difference
+}
+
+func (s Set[T]) Equal(other Set[T]) bool {
+	if s.Len() != other.Len() {
+		return false
+	}
+
+	for v := range s {
+		if !other.Contains(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s Set[T]) Filter(predicate func(T) bool) Set[T] {
+	filtered := New[T]()
+	for v := range s {
+		if predicate(v) {
+			filtered.Add(v)
+		}
+	}
+	return filtered
+}
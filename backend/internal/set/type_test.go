@@ -0,0 +1,121 @@
+package set
+
+import (
+	"slices"
+	"testing"
+)
+
+func of(vs ...string) Set[string] {
+	s := New[string]()
+	s.AddAll(vs...)
+	return s
+}
+
+func TestSetOperations(t *testing.T) {
+	tests := []struct {
+		name         string
+		a            Set[string]
+		b            Set[string]
+		union        []string
+		intersection []string
+		difference   []string
+	}{
+		{
+			name:         "both empty",
+			a:            of(),
+			b:            of(),
+			union:        []string{},
+			intersection: []string{},
+			difference:   []string{},
+		},
+		{
+			name:         "empty receiver",
+			a:            of(),
+			b:            of("mp4", "webm"),
+			union:        []string{"mp4", "webm"},
+			intersection: []string{},
+			difference:   []string{},
+		},
+		{
+			name:         "empty other",
+			a:            of("mp4", "webm"),
+			b:            of(),
+			union:        []string{"mp4", "webm"},
+			intersection: []string{},
+			difference:   []string{"mp4", "webm"},
+		},
+		{
+			name:         "disjoint",
+			a:            of("mp4", "webm"),
+			b:            of("m4a", "opus"),
+			union:        []string{"m4a", "mp4", "opus", "webm"},
+			intersection: []string{},
+			difference:   []string{"mp4", "webm"},
+		},
+		{
+			name:         "overlapping",
+			a:            of("mp4", "webm"),
+			b:            of("webm", "opus"),
+			union:        []string{"mp4", "opus", "webm"},
+			intersection: []string{"webm"},
+			difference:   []string{"mp4"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := of(test.a.ToSlice()...), of(test.b.ToSlice()...)
+
+			if got := ToSortedSlice(test.a.Union(test.b)); !slices.Equal(got, test.union) {
+				t.Errorf("union got %v, want %v", got, test.union)
+			}
+			if got := ToSortedSlice(test.a.Intersection(test.b)); !slices.Equal(got, test.intersection) {
+				t.Errorf("intersection got %v, want %v", got, test.intersection)
+			}
+			if got := ToSortedSlice(test.a.Difference(test.b)); !slices.Equal(got, test.difference) {
+				t.Errorf("difference got %v, want %v", got, test.difference)
+			}
+
+			// The operations return new sets, leaving their operands alone
+			if !test.a.Equal(a) || !test.b.Equal(b) {
+				t.Errorf("operands changed to %v and %v", test.a.ToSlice(), test.b.ToSlice())
+			}
+		})
+	}
+}
+
+func TestSetEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Set[string]
+		b    Set[string]
+		want bool
+	}{
+		{name: "both empty", a: of(), b: of(), want: true},
+		{name: "one empty", a: of(), b: of("mp4"), want: false},
+		{name: "same elements", a: of("mp4", "webm"), b: of("webm", "mp4"), want: true},
+		{name: "same size, disjoint", a: of("mp4", "webm"), b: of("m4a", "opus"), want: false},
+		{name: "subset", a: of("mp4"), b: of("mp4", "webm"), want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.a.Equal(test.b); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if got := test.b.Equal(test.a); got != test.want {
+				t.Errorf("reversed got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestToSliceEmpty(t *testing.T) {
+	// Encodes as [] rather than null
+	if got := New[string]().ToSlice(); got == nil || len(got) != 0 {
+		t.Errorf("got %#v, want an empty slice", got)
+	}
+	if got := ToSortedSlice(New[int]()); got == nil || len(got) != 0 {
+		t.Errorf("got %#v, want an empty slice", got)
+	}
+}
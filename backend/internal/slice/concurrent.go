@@ -0,0 +1,27 @@
+package slice
+
+import "sync"
+
+// MapConcurrent applies f to every item using at most limit goroutines at a
+// time, returning the results in the same order as the input
+func MapConcurrent[T, U any](slice []T, limit int, f func(T) U) []U {
+	results := make([]U, len(slice))
+	if limit <= 0 {
+		limit = 1
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, limit)
+	for i, item := range slice {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = f(item)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
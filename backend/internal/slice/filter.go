@@ -1,7 +1,8 @@
 package slice
 
 func Filter[T any](slice []T, predicate func(T) bool) []T {
-	var filtered []T
+	// Never nil, so an empty result still marshals as an empty JSON array
+	filtered := make([]T, 0)
 	for _, item := range slice {
 		if predicate(item) {
 			filtered = append(filtered, item)
@@ -0,0 +1,10 @@
+package slice
+
+func Map[T, U any](slice []T, f func(T) U) []U {
+	// Never nil, so an empty result still marshals as an empty JSON array
+	mapped := make([]U, 0, len(slice))
+	for _, item := range slice {
+		mapped = append(mapped, f(item))
+	}
+	return mapped
+}
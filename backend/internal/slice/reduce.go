@@ -0,0 +1,9 @@
+package slice
+
+func Reduce[T, U any](slice []T, initial U, f func(U, T) U) U {
+	accumulator := initial
+	for _, item := range slice {
+		accumulator = f(accumulator, item)
+	}
+	return accumulator
+}
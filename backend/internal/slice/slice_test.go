@@ -0,0 +1,83 @@
+package slice
+
+import (
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []int
+		want  []int
+	}{
+		{name: "keeps matching items in order", input: []int{1, 2, 3, 4}, want: []int{2, 4}},
+		{name: "nothing matches", input: []int{1, 3}, want: []int{}},
+		{name: "nil input", input: nil, want: []int{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Filter(test.input, func(i int) bool { return i%2 == 0 })
+			if got == nil || !slices.Equal(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	if got := Map([]int{1, 2, 3}, strconv.Itoa); !slices.Equal(got, []string{"1", "2", "3"}) {
+		t.Errorf("got %v, want [1 2 3]", got)
+	}
+	if got := Map(nil, strconv.Itoa); got == nil || len(got) != 0 {
+		t.Errorf("got %#v for nil input, want an empty slice", got)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := func(total int, i int) int { return total + i }
+	if got := Reduce([]int{1, 2, 3}, 10, sum); got != 16 {
+		t.Errorf("got %d, want 16", got)
+	}
+	if got := Reduce(nil, 10, sum); got != 10 {
+		t.Errorf("got %d for nil input, want the initial value", got)
+	}
+}
+
+func TestMapConcurrent(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+	}{
+		{name: "limited", limit: 2},
+		{name: "no limit falls back to one", limit: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var running, peak atomic.Int32
+			input := []int{1, 2, 3, 4, 5, 6}
+			got := MapConcurrent(input, test.limit, func(i int) int {
+				now := running.Add(1)
+				defer running.Add(-1)
+				for {
+					seen := peak.Load()
+					if now <= seen || peak.CompareAndSwap(seen, now) {
+						break
+					}
+				}
+				return i * i
+			})
+
+			if want := []int{1, 4, 9, 16, 25, 36}; !slices.Equal(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			if limit := int32(max(test.limit, 1)); peak.Load() > limit {
+				t.Errorf("got %d running at once, want at most %d", peak.Load(), limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package transient
+
+import (
+	"errors"
+	"time"
+)
+
+// Error marks a failure that is expected to go away on its own, along with how
+// long the caller should wait before trying again
+type Error struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func New(err error, retryAfter time.Duration) *Error {
+	return &Error{Err: err, RetryAfter: retryAfter}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns the wait duration of the first transient error in err's chain
+func RetryAfter(err error) (time.Duration, bool) {
+	var transientErr *Error
+	if !errors.As(err, &transientErr) {
+		return 0, false
+	}
+	return transientErr.RetryAfter, true
+}
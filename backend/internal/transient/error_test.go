@@ -0,0 +1,49 @@
+package transient
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	errRateLimited := errors.New("rate limited")
+
+	tests := []struct {
+		name      string
+		err       error
+		retry     time.Duration
+		transient bool
+	}{
+		{name: "transient", err: New(errRateLimited, 30*time.Second), retry: 30 * time.Second, transient: true},
+		{name: "wrapped", err: fmt.Errorf("fetch media: %w", New(errRateLimited, time.Minute)), retry: time.Minute, transient: true},
+		{name: "outermost wins", err: New(New(errRateLimited, time.Minute), 5*time.Second), retry: 5 * time.Second, transient: true},
+		{name: "permanent", err: errRateLimited},
+		{name: "nil", err: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			retry, ok := RetryAfter(test.err)
+			if ok != test.transient {
+				t.Errorf("got transient %v, want %v", ok, test.transient)
+			}
+			if retry != test.retry {
+				t.Errorf("got retry after %v, want %v", retry, test.retry)
+			}
+		})
+	}
+}
+
+func TestErrorUnwraps(t *testing.T) {
+	errRateLimited := errors.New("rate limited")
+	err := New(fmt.Errorf("%w: HTTP Error 429", errRateLimited), time.Minute)
+
+	if !errors.Is(err, errRateLimited) {
+		t.Errorf("%v doesn't wrap %v", err, errRateLimited)
+	}
+	if err.Error() != "rate limited: HTTP Error 429" {
+		t.Errorf("got message %q, want the wrapped error's", err.Error())
+	}
+}
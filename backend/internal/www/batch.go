@@ -0,0 +1,125 @@
+package www
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"media-downloader/internal/media"
+	"media-downloader/internal/media/info"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// Most URLs a single batch may contain
+	maxBatchSize = 50
+
+	// How many URLs of a batch are fetched at once, yt-dlp's own concurrency
+	// limit still applies on top
+	batchWorkers = 4
+
+	// Upper bound on a whole batch, URLs not done by then report an error
+	batchTimeout = 2 * time.Minute
+
+	maxBatchBodyBytes = 1 << 20
+)
+
+type batchRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// batchEntry holds either the media of a URL or why it couldn't be fetched
+type batchEntry struct {
+	Media *info.Media    `json:"media,omitempty"`
+	Error *errorResponse `json:"error,omitempty"`
+}
+
+// qualityBatchHandler looks up the formats of several URLs at once. A URL
+// failing only fails its own entry, the response maps every URL to its result
+func qualityBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var request batchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid JSON body, expected {\"urls\": [...]}")
+		return
+	}
+	if len(request.URLs) == 0 {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing urls")
+		return
+	}
+	if len(request.URLs) > maxBatchSize {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, fmt.Sprintf("At most %d urls can be looked up at once", maxBatchSize))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(requestContext(r, ParseQuery(r)), batchTimeout)
+	defer cancel()
+
+	urls := make(chan string)
+	var mu sync.Mutex
+	results := make(map[string]batchEntry, len(request.URLs))
+	var wg sync.WaitGroup
+	for range min(batchWorkers, len(request.URLs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range urls {
+				entry := fetchBatchEntry(ctx, url)
+				mu.Lock()
+				results[url] = entry
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, url := range request.URLs {
+		urls <- url
+	}
+	close(urls)
+	wg.Wait()
+
+	// Entries are named on their own so URLs used as keys stay untouched
+	response := make(map[string]json.RawMessage, len(results))
+	for url, entry := range results {
+		entryBytes, err := marshalJSON(entry)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+		response[url] = entryBytes
+	}
+
+	jsonBytes, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
+	_, err = w.Write(jsonBytes)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+}
+
+func fetchBatchEntry(ctx context.Context, url string) batchEntry {
+	mediaInfo, err := media.FetchMedia(ctx, url)
+	if err != nil && ctx.Err() != nil {
+		return batchEntry{Error: &errorResponse{Error: "The batch took too long to complete", Code: codeTemporarilyUnavailable}}
+	}
+	if err != nil {
+		_, code, message := classifyMediaError(err)
+		return batchEntry{Error: &errorResponse{Error: message, Code: code}}
+	}
+
+	mediaInfo.CleanFormats()
+	mediaInfo.SortFormats()
+	mediaInfo.FilterStats = nil
+	return batchEntry{Media: mediaInfo}
+}
@@ -0,0 +1,56 @@
+package www
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestQualityBatchHandlerRejects(t *testing.T) {
+	tooMany := make([]string, maxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf(`"https://www.youtube.com/watch?v=%011d"`, i)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "wrong method", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed, wantCode: codeMethodNotAllowed},
+		{name: "invalid json", method: http.MethodPost, body: "urls", wantStatus: http.StatusBadRequest, wantCode: codeInvalidParameter},
+		{name: "no urls", method: http.MethodPost, body: `{"urls": []}`, wantStatus: http.StatusBadRequest, wantCode: codeMissingParameter},
+		{
+			name:       "too many urls",
+			method:     http.MethodPost,
+			body:       `{"urls": [` + strings.Join(tooMany, ",") + `]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   codeInvalidParameter,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "/api/quality/batch", strings.NewReader(test.body))
+			recorder := httptest.NewRecorder()
+
+			qualityBatchHandler(recorder, request)
+
+			if recorder.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, test.wantStatus)
+			}
+			var response errorResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid error response %q: %v", recorder.Body.String(), err)
+			}
+			if response.Code != test.wantCode {
+				t.Errorf("got code %q, want %q", response.Code, test.wantCode)
+			}
+		})
+	}
+}
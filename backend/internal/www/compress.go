@@ -0,0 +1,139 @@
+package www
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"media-downloader/internal/set"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// JSON responses smaller than this aren't worth compressing
+const minCompressBytes = 1024
+
+// compressor is a gzip or zlib writer
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressWriter compresses the body of complete JSON responses, deciding
+// once the headers are written. Everything else, downloads in particular,
+// passes through untouched.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	compressor  compressor
+	wroteHeader bool
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.wroteHeader = true
+		c.startCompression(status)
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *compressWriter) startCompression(status int) {
+	header := c.Header()
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return
+	}
+	header.Add("Vary", "Accept-Encoding")
+
+	// Only whole bodies of known size are compressed, never a range of a
+	// file or a stream
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if c.encoding == "" || status != http.StatusOK || err != nil || length < minCompressBytes ||
+		header.Get("Content-Encoding") != "" || header.Get("Content-Disposition") != "" {
+		return
+	}
+
+	// The length is of the uncompressed body, and the compressed bytes are
+	// a different representation than the strong ETag was computed for
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", c.encoding)
+	if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		header.Set("ETag", "W/"+etag)
+	}
+
+	if c.encoding == "gzip" {
+		c.compressor = gzip.NewWriter(c.ResponseWriter)
+	} else {
+		c.compressor = zlib.NewWriter(c.ResponseWriter)
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.compressor == nil {
+		return c.ResponseWriter.Write(p)
+	}
+	return c.compressor.Write(p)
+}
+
+func (c *compressWriter) Flush() {
+	if c.compressor != nil {
+		_ = c.compressor.Flush()
+	}
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// close writes the end of the compressed stream, if any
+func (c *compressWriter) close() {
+	if c.compressor != nil {
+		_ = c.compressor.Close()
+	}
+}
+
+// withCompression gzips, or deflates for clients only accepting that, JSON
+// responses of at least minCompressBytes
+func withCompression(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := &compressWriter{ResponseWriter: w}
+		// The headers of HEAD requests describe the uncompressed body
+		if r.Method != http.MethodHead {
+			writer.encoding = acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		}
+		defer writer.close()
+
+		next.ServeHTTP(writer, r)
+	})
+}
+
+// acceptedEncoding picks gzip or deflate from an Accept-Encoding header,
+// empty when the client accepts neither
+func acceptedEncoding(header string) string {
+	accepted := set.New[string]()
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(value, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+		accepted.Add(strings.ToLower(strings.TrimSpace(name)))
+	}
+
+	switch {
+	case accepted.Contains("gzip") || accepted.Contains("*"):
+		return "gzip"
+	case accepted.Contains("deflate"):
+		return "deflate"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,92 @@
+package www
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "gzip, deflate, br", want: "gzip"},
+		{header: "deflate", want: "deflate"},
+		{header: "GZIP;q=0.5", want: "gzip"},
+		{header: "gzip;q=0, deflate", want: "deflate"},
+		{header: "*", want: "gzip"},
+		{header: "br, identity", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.header, func(t *testing.T) {
+			if got := acceptedEncoding(test.header); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithCompression(t *testing.T) {
+	large := `{"title":"` + strings.Repeat("x", minCompressBytes) + `"}`
+
+	tests := []struct {
+		name         string
+		contentType  string
+		body         string
+		wantEncoding string
+	}{
+		{name: "large json", contentType: "application/json", body: large, wantEncoding: "gzip"},
+		{name: "small json", contentType: "application/json", body: `{"title":"x"}`},
+		{name: "download", contentType: "video/mp4", body: large},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := withCompression(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.Header().Set("Content-Length", fmt.Sprintf("%d", len(test.body)))
+				w.Header().Set("ETag", `"abc"`)
+				_, _ = io.WriteString(w, test.body)
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/api/quality", nil)
+			request.Header.Set("Accept-Encoding", "gzip")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if got := recorder.Header().Get("Content-Encoding"); got != test.wantEncoding {
+				t.Fatalf("got encoding %q, want %q", got, test.wantEncoding)
+			}
+
+			body := io.Reader(recorder.Body)
+			if test.wantEncoding == "gzip" {
+				reader, err := gzip.NewReader(recorder.Body)
+				if err != nil {
+					t.Fatalf("invalid gzip body: %v", err)
+				}
+				body = reader
+
+				if got := recorder.Header().Get("ETag"); got != `W/"abc"` {
+					t.Errorf("got etag %q, want the weak one", got)
+				}
+				if got := recorder.Header().Get("Content-Length"); got != "" {
+					t.Errorf("got the uncompressed length %s", got)
+				}
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			if string(got) != test.body {
+				t.Errorf("got a body of %d bytes, want %d", len(got), len(test.body))
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package www
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Origins allowed to call the API, "*" allows any origin
+var allowedOrigins = []string{"*"}
+
+// Headers browsers may read from responses, needed for the download filename,
+// conditional requests and the back off hint of rate limited requests
+const exposedHeaders = "Content-Disposition, Content-Length, ETag, Retry-After"
+
+// ParseOrigins splits a comma separated list of origins, defaulting to "*"
+func ParseOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	if len(origins) == 0 {
+		return []string{"*"}
+	}
+	return origins
+}
+
+// withCORS adds the CORS headers to every response and answers preflight
+// requests. A configured origin list echoes the request's origin when it
+// matches, which unlike "*" works with credentialed requests
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := originAllowed(origin)
+
+		w.Header().Add("Vary", "Origin")
+		if allowed {
+			if allowsAnyOrigin() {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
+		}
+
+		// Preflight requests never reach the handlers
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, DELETE, OPTIONS")
+				if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+					w.Header().Set("Access-Control-Allow-Headers", headers)
+				}
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func allowsAnyOrigin() bool {
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func originAllowed(origin string) bool {
+	if allowsAnyOrigin() {
+		return true
+	}
+	if origin == "" {
+		return false
+	}
+
+	for _, allowed := range allowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package www
+
+import (
+	"fmt"
+	"media-downloader/internal/media"
+	"net/http"
+)
+
+// debugRawHandler returns yt-dlp's output for a URL as is, only registered
+// when debug endpoints are enabled
+func debugRawHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := ParseQuery(r)
+	urlParam, err := query.Get("url")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing url parameter")
+		return
+	}
+
+	raw, err := media.FetchRawInfo(requestContext(r, query), urlParam)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	// Passed through unchanged, the key naming setting doesn't apply
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(raw)))
+	_, err = w.Write(raw)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+}
@@ -0,0 +1,83 @@
+package www
+
+import (
+	"errors"
+	"io"
+	"media-downloader/internal/media/info"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// failingDownload streams body, then fails on Close like a yt-dlp that
+// exited with an error after its output ended
+type failingDownload struct {
+	io.Reader
+	closeErr error
+}
+
+func (d *failingDownload) Close() error {
+	return d.closeErr
+}
+
+func TestWriteDownloadCloseError(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		size       uint64
+		rangeValue string
+		closeErr   error
+		wantAbort  bool
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "success", body: "0123456789", wantStatus: http.StatusOK, wantBody: "0123456789"},
+		{name: "failure after streaming", body: "01234", closeErr: errors.New("yt-dlp failed"), wantAbort: true},
+		{name: "failure before any output", closeErr: errors.New("yt-dlp failed"), wantStatus: http.StatusInternalServerError},
+		{name: "range", body: "0123456789", size: 10, rangeValue: "bytes=2-4", wantStatus: http.StatusPartialContent, wantBody: "234"},
+		{name: "range cutting yt-dlp off", body: "0123456789", size: 10, rangeValue: "bytes=2-4", closeErr: errors.New("broken pipe"), wantStatus: http.StatusPartialContent, wantBody: "234"},
+		{name: "range to the end failing", body: "0123456789", size: 10, rangeValue: "bytes=5-", closeErr: errors.New("yt-dlp failed"), wantAbort: true},
+		{name: "range without exact size", body: "0123456789", rangeValue: "bytes=2-4", wantStatus: http.StatusOK, wantBody: "0123456789"},
+		{name: "range past the end", body: "0123456789", size: 10, rangeValue: "bytes=20-", wantStatus: http.StatusRequestedRangeNotSatisfiable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/download?url=https://www.youtube.com/watch?v=dQw4w9WgXcQ", nil)
+			if test.rangeValue != "" {
+				request.Header.Set("Range", test.rangeValue)
+			}
+			recorder := httptest.NewRecorder()
+			media := &info.Media{Title: "Test video"}
+			format := &info.Format{Extension: "mp4", Size: test.size}
+			download := &failingDownload{Reader: strings.NewReader(test.body), closeErr: test.closeErr}
+
+			aborted := func() (aborted bool) {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						if recovered != http.ErrAbortHandler {
+							panic(recovered)
+						}
+						aborted = true
+					}
+				}()
+				writeDownload(recorder, request, media, format, download)
+				return false
+			}()
+
+			if aborted != test.wantAbort {
+				t.Fatalf("got aborted %v, want %v", aborted, test.wantAbort)
+			}
+			if test.wantAbort {
+				return
+			}
+			if recorder.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, test.wantStatus)
+			}
+			if test.wantBody != "" && recorder.Body.String() != test.wantBody {
+				t.Errorf("got body %q, want %q", recorder.Body.String(), test.wantBody)
+			}
+		})
+	}
+}
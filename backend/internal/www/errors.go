@@ -0,0 +1,127 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"math"
+	"media-downloader/internal/media"
+	"media-downloader/internal/media/ffmpeg"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/media/sources"
+	"media-downloader/internal/media/ytdlp"
+	"media-downloader/internal/metrics"
+	"media-downloader/internal/transient"
+	"net/http"
+	"strconv"
+)
+
+// Stable, machine readable error codes the frontend can switch on
+const (
+	codeMethodNotAllowed       = "method_not_allowed"
+	codeMissingParameter       = "missing_parameter"
+	codeInvalidParameter       = "invalid_parameter"
+	codeUnsupportedSource      = "unsupported_source"
+	codeInvalidURL             = "invalid_url"
+	codeNotFound               = "not_found"
+	codeVideoUnavailable       = "video_unavailable"
+	codePrivateVideo           = "private_video"
+	codeGeoBlocked             = "geo_blocked"
+	codeAuthenticationRequired = "authentication_required"
+	codeDRMProtected           = "drm_protected"
+	codeLiveStream             = "live_stream"
+	codeNoVideo                = "no_video"
+	codeFeatureDisabled        = "feature_disabled"
+	codeFFmpegUnavailable      = "ffmpeg_unavailable"
+	codeTemporarilyUnavailable = "temporarily_unavailable"
+	codeRateLimited            = "rate_limited"
+	codeTooLarge               = "too_large"
+	codeSizeUnknown            = "size_unknown"
+	codeRangeNotSatisfiable    = "range_not_satisfiable"
+	codeJobNotReady            = "job_not_ready"
+	codeUpstreamFailed         = "upstream_failed"
+	codeInternal               = "internal_error"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	metrics.Errors.WithLabelValues(code).Inc()
+
+	jsonBytes, err := marshalJSON(errorResponse{Error: message, Code: code})
+	if err != nil {
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(jsonBytes)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, _ = w.Write(jsonBytes)
+}
+
+// writeMediaError maps an error from the media package to the status and
+// code telling the client whether it, the server or the source is at fault
+func writeMediaError(w http.ResponseWriter, err error) {
+	// The client is gone, nobody is left to read the response
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	// Transient failures tell the client when to try again
+	if retryAfter, ok := transient.RetryAfter(err); ok {
+		// Always ask for at least a second, rounding up partial seconds
+		seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+
+	status, code, message := classifyMediaError(err)
+	writeError(w, status, code, message)
+}
+
+// classifyMediaError returns the status, code and message of a media error
+func classifyMediaError(err error) (int, string, string) {
+	if _, ok := transient.RetryAfter(err); ok {
+		return http.StatusServiceUnavailable, codeTemporarilyUnavailable, err.Error()
+	}
+
+	switch {
+	case errors.Is(err, sources.ErrInvalidURL):
+		return http.StatusBadRequest, codeInvalidURL, err.Error()
+	case errors.Is(err, media.ErrUnsupportedSource):
+		return http.StatusBadRequest, codeUnsupportedSource, err.Error()
+	case errors.Is(err, media.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge, codeTooLarge, err.Error()
+	case errors.Is(err, media.ErrInvalidRequest):
+		return http.StatusBadRequest, codeInvalidParameter, err.Error()
+	case errors.Is(err, info.ErrFormatNotFound), errors.Is(err, info.ErrLanguageNotAvailable):
+		return http.StatusNotFound, codeNotFound, err.Error()
+	case errors.Is(err, ytdlp.ErrVideoUnavailable):
+		return http.StatusNotFound, codeVideoUnavailable, "The video is unavailable, it may have been removed"
+	case errors.Is(err, ytdlp.ErrNoVideo):
+		return http.StatusUnprocessableEntity, codeNoVideo, "The post contains only images, there is no video or audio to download"
+	case errors.Is(err, ytdlp.ErrLoginRequired):
+		return http.StatusForbidden, codeAuthenticationRequired, "The source requires logging in, cookies for it need to be configured"
+	case errors.Is(err, ytdlp.ErrPrivateVideo):
+		return http.StatusForbidden, codePrivateVideo, "The video is private"
+	case errors.Is(err, ytdlp.ErrGeoBlocked):
+		return http.StatusForbidden, codeGeoBlocked, "The video is not available in the server's region"
+	case errors.Is(err, info.ErrSizeUnknown):
+		return http.StatusUnprocessableEntity, codeSizeUnknown, "The size can't be estimated, the source reports neither size nor bitrate"
+	case errors.Is(err, info.ErrLiveStream):
+		return http.StatusUnprocessableEntity, codeLiveStream, "Live streams can't be downloaded while they are live, pass allow_live=true to grab the stream anyway"
+	case errors.Is(err, info.ErrDRMProtected):
+		return http.StatusForbidden, codeDRMProtected, err.Error()
+	case errors.Is(err, ytdlp.ErrInvalidCookiesProfile):
+		return http.StatusBadRequest, codeInvalidParameter, err.Error()
+	case errors.Is(err, ytdlp.ErrPremiumRequiresCookies):
+		return http.StatusForbidden, codeAuthenticationRequired, err.Error()
+	case errors.Is(err, ffmpeg.ErrNotInstalled):
+		return http.StatusServiceUnavailable, codeFFmpegUnavailable, err.Error()
+	default:
+		return http.StatusBadGateway, codeUpstreamFailed, err.Error()
+	}
+}
@@ -0,0 +1,223 @@
+package www
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"media-downloader/internal/media"
+	"media-downloader/internal/media/ffmpeg"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/media/sources"
+	"media-downloader/internal/media/ytdlp"
+	"media-downloader/internal/ratelimit"
+	"media-downloader/internal/transient"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteMediaError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		code       string
+		retryAfter string
+	}{
+		{
+			name:       "rate limit with reported retry time",
+			err:        transient.New(fmt.Errorf("%w: HTTP Error 429: retry after 30 seconds", ytdlp.ErrRateLimited), 30*time.Second),
+			status:     http.StatusServiceUnavailable,
+			code:       codeTemporarilyUnavailable,
+			retryAfter: "30",
+		},
+		{
+			name:       "rate limit without reported retry time",
+			err:        transient.New(ytdlp.ErrRateLimited, time.Minute),
+			status:     http.StatusServiceUnavailable,
+			code:       codeTemporarilyUnavailable,
+			retryAfter: "60",
+		},
+		{
+			name:       "wrapped transient error",
+			err:        fmt.Errorf("fetch media: %w", transient.New(ytdlp.ErrRateLimited, 10*time.Second)),
+			status:     http.StatusServiceUnavailable,
+			code:       codeTemporarilyUnavailable,
+			retryAfter: "10",
+		},
+		{
+			name:       "partial seconds round up",
+			err:        transient.New(ytdlp.ErrRateLimited, 1500*time.Millisecond),
+			status:     http.StatusServiceUnavailable,
+			code:       codeTemporarilyUnavailable,
+			retryAfter: "2",
+		},
+		{
+			name:       "at least a second",
+			err:        transient.New(ytdlp.ErrRateLimited, 0),
+			status:     http.StatusServiceUnavailable,
+			code:       codeTemporarilyUnavailable,
+			retryAfter: "1",
+		},
+		{
+			name:   "unsupported source",
+			err:    fmt.Errorf("%w: Unknown", media.ErrUnsupportedSource),
+			status: http.StatusBadRequest,
+			code:   codeUnsupportedSource,
+		},
+		{
+			name:   "invalid url",
+			err:    fmt.Errorf("%w: internal hosts are not allowed", sources.ErrInvalidURL),
+			status: http.StatusBadRequest,
+			code:   codeInvalidURL,
+		},
+		{
+			name:   "invalid request",
+			err:    fmt.Errorf("%w: url does not belong to source YouTube", media.ErrInvalidRequest),
+			status: http.StatusBadRequest,
+			code:   codeInvalidParameter,
+		},
+		{
+			name:   "format not found",
+			err:    fmt.Errorf("%w: %q", info.ErrFormatNotFound, "137"),
+			status: http.StatusNotFound,
+			code:   codeNotFound,
+		},
+		{
+			name:   "language not available",
+			err:    fmt.Errorf("%w: fr", info.ErrLanguageNotAvailable),
+			status: http.StatusNotFound,
+			code:   codeNotFound,
+		},
+		{
+			name:   "video unavailable",
+			err:    fmt.Errorf("%w: removed", ytdlp.ErrVideoUnavailable),
+			status: http.StatusNotFound,
+			code:   codeVideoUnavailable,
+		},
+		{
+			name:   "size unknown",
+			err:    fmt.Errorf("%w: \"137\" and \"140\"", info.ErrSizeUnknown),
+			status: http.StatusUnprocessableEntity,
+			code:   codeSizeUnknown,
+		},
+		{
+			name:   "no video",
+			err:    fmt.Errorf("%w: there is no video in this post", ytdlp.ErrNoVideo),
+			status: http.StatusUnprocessableEntity,
+			code:   codeNoVideo,
+		},
+		{
+			name:   "login required",
+			err:    fmt.Errorf("%w: use --cookies", ytdlp.ErrLoginRequired),
+			status: http.StatusForbidden,
+			code:   codeAuthenticationRequired,
+		},
+		{
+			name:   "private video",
+			err:    fmt.Errorf("%w: sign in", ytdlp.ErrPrivateVideo),
+			status: http.StatusForbidden,
+			code:   codePrivateVideo,
+		},
+		{
+			name:   "geo blocked",
+			err:    fmt.Errorf("%w: in your country", ytdlp.ErrGeoBlocked),
+			status: http.StatusForbidden,
+			code:   codeGeoBlocked,
+		},
+		{
+			name:   "live stream",
+			err:    info.ErrLiveStream,
+			status: http.StatusUnprocessableEntity,
+			code:   codeLiveStream,
+		},
+		{
+			name:   "too large",
+			err:    fmt.Errorf("%w: 3000 bytes, the limit is 2000", media.ErrTooLarge),
+			status: http.StatusRequestEntityTooLarge,
+			code:   codeTooLarge,
+		},
+		{
+			name:   "drm protected",
+			err:    fmt.Errorf("%w: %q", info.ErrDRMProtected, "137"),
+			status: http.StatusForbidden,
+			code:   codeDRMProtected,
+		},
+		{
+			name:   "premium without cookies",
+			err:    ytdlp.ErrPremiumRequiresCookies,
+			status: http.StatusForbidden,
+			code:   codeAuthenticationRequired,
+		},
+		{
+			name:   "ffmpeg missing",
+			err:    ffmpeg.ErrNotInstalled,
+			status: http.StatusServiceUnavailable,
+			code:   codeFFmpegUnavailable,
+		},
+		{
+			name:   "anything else",
+			err:    errors.New("yt-dlp failed: something broke"),
+			status: http.StatusBadGateway,
+			code:   codeUpstreamFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			writeMediaError(recorder, test.err)
+
+			if recorder.Code != test.status {
+				t.Errorf("got status %d, want %d", recorder.Code, test.status)
+			}
+			if got := recorder.Header().Get("Retry-After"); got != test.retryAfter {
+				t.Errorf("got Retry-After %q, want %q", got, test.retryAfter)
+			}
+
+			var response errorResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("body %q isn't an error response: %v", recorder.Body, err)
+			}
+			if response.Code != test.code {
+				t.Errorf("got code %q, want %q", response.Code, test.code)
+			}
+		})
+	}
+}
+
+func TestWriteMediaErrorCanceled(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeMediaError(recorder, fmt.Errorf("fetch media: %w", context.Canceled))
+
+	// Nobody is left to read a response
+	if recorder.Body.Len() > 0 {
+		t.Errorf("got body %q for a cancelled request", recorder.Body)
+	}
+}
+
+func TestClientLimitRetryAfter(t *testing.T) {
+	previous := clientLimiter
+	clientLimiter = ratelimit.NewKeyed(60, 1)
+	t.Cleanup(func() { clientLimiter = previous })
+
+	handler := withClientLimit(func(w http.ResponseWriter, r *http.Request) {})
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/api/quality", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("first request got status %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/api/quality", nil))
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request got status %d, want %d", recorder.Code, http.StatusTooManyRequests)
+	}
+	// A request a second comes back after at most that second
+	if got := recorder.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("got Retry-After %q, want %q", got, "1")
+	}
+}
@@ -0,0 +1,64 @@
+package www
+
+import (
+	"fmt"
+	"media-downloader/internal/media"
+	"net/http"
+)
+
+type mergedSizeResponse struct {
+	Size        uint64 `json:"size"`
+	Approximate bool   `json:"approximate"`
+}
+
+func mergedSizeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := ParseQuery(r)
+	urlParam, err := query.Get("url")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing url parameter")
+		return
+	}
+
+	videoIdentifier, err := query.Get("video_identifier")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing video_identifier parameter")
+		return
+	}
+
+	audioIdentifier, err := query.Get("audio_identifier")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing audio_identifier parameter")
+		return
+	}
+
+	mediaInfo, err := media.FetchMedia(requestContext(r, query), urlParam)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	size, approximate, err := mediaInfo.EstimateMergedSize(videoIdentifier, audioIdentifier)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	jsonBytes, err := marshalJSON(mergedSizeResponse{Size: size, Approximate: approximate})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
+	_, err = w.Write(jsonBytes)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+}
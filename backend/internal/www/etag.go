@@ -0,0 +1,49 @@
+package www
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// etagFor returns a strong ETag of a response body
+func etagFor(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified sets the ETag and caching headers and reports whether the
+// client's If-None-Match already matches, in which case 304 was written.
+// The response may be cached for as long as it stays unchanged, maxAge.
+func notModified(w http.ResponseWriter, r *http.Request, body []byte, maxAge time.Duration) bool {
+	etag := etagFor(body)
+	w.Header().Set("ETag", etag)
+
+	// The response depends on the request's cookies profile
+	if seconds := int(maxAge.Seconds()); seconds > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", seconds))
+	} else {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+
+	if !etagMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches compares weakly, as RFC 9110 requires for If-None-Match
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package www
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	body := []byte(`{"title":"Test video"}`)
+	etag := etagFor(body)
+
+	tests := []struct {
+		name         string
+		ifNoneMatch  string
+		maxAge       time.Duration
+		wantMatch    bool
+		cacheControl string
+	}{
+		{name: "no header", maxAge: time.Minute, cacheControl: "private, max-age=60"},
+		{name: "same etag", ifNoneMatch: etag, maxAge: time.Minute, wantMatch: true, cacheControl: "private, max-age=60"},
+		{name: "weak etag", ifNoneMatch: "W/" + etag, wantMatch: true, cacheControl: "private, no-cache"},
+		{name: "one of several", ifNoneMatch: `"other", ` + etag, wantMatch: true, cacheControl: "private, no-cache"},
+		{name: "wildcard", ifNoneMatch: "*", wantMatch: true, cacheControl: "private, no-cache"},
+		{name: "different etag", ifNoneMatch: `"other"`, cacheControl: "private, no-cache"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/quality", nil)
+			if test.ifNoneMatch != "" {
+				request.Header.Set("If-None-Match", test.ifNoneMatch)
+			}
+			recorder := httptest.NewRecorder()
+
+			if got := notModified(recorder, request, body, test.maxAge); got != test.wantMatch {
+				t.Errorf("got %v, want %v", got, test.wantMatch)
+			}
+			if test.wantMatch && recorder.Code != http.StatusNotModified {
+				t.Errorf("got status %d, want %d", recorder.Code, http.StatusNotModified)
+			}
+			if got := recorder.Header().Get("ETag"); got != etag {
+				t.Errorf("got etag %q, want %q", got, etag)
+			}
+			if got := recorder.Header().Get("Cache-Control"); got != test.cacheControl {
+				t.Errorf("got cache control %q, want %q", got, test.cacheControl)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package www
+
+import (
+	"fmt"
+	"media-downloader/internal/media/info"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Keeps the name within the 255 byte limit of common filesystems
+const maxFilenameBytes = 200
+
+// SanitizeFilename builds a filename from a media title and extension that
+// is safe to save on any common filesystem, keeping Unicode characters
+func SanitizeFilename(title string, ext string) string {
+	var builder strings.Builder
+	lastSpace := false
+	for _, r := range title {
+		// Collapse runs of whitespace such as newlines and tabs into a single
+		// space, before they'd be replaced as control characters below
+		if unicode.IsSpace(r) {
+			if lastSpace {
+				continue
+			}
+			r = ' '
+		}
+		lastSpace = r == ' '
+
+		// Path separators, characters Windows rejects and control characters
+		if strings.ContainsRune(`/\:*?"<>|`, r) || unicode.IsControl(r) {
+			r = '_'
+		}
+
+		builder.WriteRune(r)
+	}
+
+	// Leading dots would hide the file or make it a relative path, Windows
+	// drops trailing dots and spaces on its own
+	name := strings.Trim(builder.String(), " .")
+	name = truncateUTF8(name, maxFilenameBytes)
+	if name == "" {
+		name = "download"
+	}
+
+	ext = strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, ext)
+	if ext == "" {
+		return name
+	}
+	return name + "." + ext
+}
+
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+
+	// Back up to the start of the rune that crosses the limit
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return strings.TrimRight(s[:cut], " .")
+}
+
+// Template the filename of downloads is built from unless the request
+// brings its own, the title by default
+var filenameTemplate = "{title}"
+
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]*)\}`)
+
+// Placeholders a filename template can use, filled from the media and format
+var placeholders = map[string]func(media *info.Media, format *info.Format) string{
+	"title":       func(media *info.Media, format *info.Format) string { return media.FileTitle() },
+	"raw_title":   func(media *info.Media, format *info.Format) string { return media.Title },
+	"id":          func(media *info.Media, format *info.Format) string { return media.ID },
+	"uploader":    func(media *info.Media, format *info.Format) string { return media.Uploader },
+	"upload_date": func(media *info.Media, format *info.Format) string { return media.UploadDate },
+	"source":      func(media *info.Media, format *info.Format) string { return format.Source.String() },
+	"format_id":   func(media *info.Media, format *info.Format) string { return format.SourceIdentifier },
+	"ext":         func(media *info.Media, format *info.Format) string { return format.Extension },
+}
+
+// validateFilenameTemplate rejects templates with placeholders that don't
+// exist, like "{uploader} - {title} [{id}]". Braces around anything that
+// isn't a lowercase name are kept literally.
+func validateFilenameTemplate(template string) error {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if _, ok := placeholders[match[1]]; !ok {
+			return fmt.Errorf("unknown placeholder %s in filename template", match[0])
+		}
+	}
+	return nil
+}
+
+// templateFilename expands an already validated template for a format and
+// sanitizes the result. The extension is always appended, a template ending
+// in .{ext} doesn't end up with it twice.
+func templateFilename(template string, media *info.Media, format *info.Format) string {
+	template = strings.TrimSuffix(template, ".{ext}")
+	name := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := placeholders[strings.Trim(placeholder, "{}")]
+		if !ok {
+			return placeholder
+		}
+		return value(media, format)
+	})
+	return SanitizeFilename(name, format.Extension)
+}
+
+// contentDisposition returns an attachment header value with an ASCII only
+// filename for old clients and the full name encoded as per RFC 5987
+func contentDisposition(filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r >= utf8.RuneSelf || r == '%' {
+			return '_'
+		}
+		return r
+	}, filename)
+
+	if fallback == filename {
+		return fmt.Sprintf("attachment; filename=\"%s\"", filename)
+	}
+	return fmt.Sprintf("attachment; filename=\"%s\"; filename*=UTF-8''%s", fallback, encodeRFC5987(filename))
+}
+
+// encodeRFC5987 percent encodes every byte that isn't an attr-char
+func encodeRFC5987(s string) string {
+	const attrChars = "!#$&+-.^_`|~"
+
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < utf8.RuneSelf && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || strings.IndexByte(attrChars, c) >= 0) {
+			builder.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&builder, "%%%02X", c)
+	}
+	return builder.String()
+}
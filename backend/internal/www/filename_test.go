@@ -0,0 +1,131 @@
+package www
+
+import (
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/media/sources"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		ext   string
+		want  string
+	}{
+		{name: "plain", title: "My Video", ext: "mp4", want: "My Video.mp4"},
+		{name: "emoji", title: "Party 🎉 time 🎶", ext: "mp4", want: "Party 🎉 time 🎶.mp4"},
+		{name: "non-latin", title: "日本語のタイトル", ext: "webm", want: "日本語のタイトル.webm"},
+		{name: "path traversal", title: "../../etc/passwd", ext: "mp4", want: "_.._etc_passwd.mp4"},
+		{name: "windows path traversal", title: `..\..\Windows\System32`, ext: "mp4", want: "_.._Windows_System32.mp4"},
+		{name: "absolute path", title: "/etc/passwd", ext: "mp4", want: "_etc_passwd.mp4"},
+		{name: "hidden file", title: ".bashrc", ext: "mp4", want: "bashrc.mp4"},
+		{name: "newlines and tabs", title: "line one\n\n\tline two", ext: "mp4", want: "line one line two.mp4"},
+		{name: "reserved characters", title: `a "quoted" <title>: yes? *no* |pipe|`, ext: "mp4", want: "a _quoted_ _title__ yes_ _no_ _pipe_.mp4"},
+		{name: "only dots", title: "...", ext: "mp4", want: "download.mp4"},
+		{name: "empty", title: "", ext: "mp4", want: "download.mp4"},
+		{name: "extension traversal", title: "video", ext: "../mp4", want: "video.mp4"},
+		{name: "no extension", title: "video", ext: "", want: "video"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := SanitizeFilename(test.title, test.ext); got != test.want {
+				t.Errorf("SanitizeFilename(%q, %q) = %q, want %q", test.title, test.ext, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilenameTruncatesRunes(t *testing.T) {
+	// Four bytes each, so the limit falls in the middle of one
+	name := SanitizeFilename(strings.Repeat("🎉", 60)+"x", "mp4")
+	if name != strings.Repeat("🎉", 50)+".mp4" {
+		t.Errorf("got %q, want 50 whole emoji", name)
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{
+			name:     "ascii",
+			filename: "My Video.mp4",
+			want:     `attachment; filename="My Video.mp4"`,
+		},
+		{
+			name:     "emoji",
+			filename: "Party 🎉.mp4",
+			want:     `attachment; filename="Party _.mp4"; filename*=UTF-8''Party%20%F0%9F%8E%89.mp4`,
+		},
+		{
+			name:     "percent sign",
+			filename: "100% café.mp4",
+			want:     `attachment; filename="100_ caf_.mp4"; filename*=UTF-8''100%25%20caf%C3%A9.mp4`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := contentDisposition(test.filename); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateFilenameTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{template: "{title}"},
+		{template: "{uploader} - {title} [{id}]"},
+		{template: "{upload_date} {raw_title}.{ext}"},
+		{template: "{Title} {2024}"},
+		{template: "{title} {views}", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.template, func(t *testing.T) {
+			if err := validateFilenameTemplate(test.template); (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateFilename(t *testing.T) {
+	media := &info.Media{
+		ID:         "dQw4w9WgXcQ",
+		Title:      "Test video (Official Video)",
+		CleanTitle: "Test video",
+		Uploader:   "Uploader",
+		UploadDate: "20091025",
+	}
+	format := &info.Format{Extension: "mp4", Source: sources.YouTube, SourceIdentifier: "137"}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: "{title}", want: "Test video.mp4"},
+		{template: "{uploader} - {title} [{id}]", want: "Uploader - Test video [dQw4w9WgXcQ].mp4"},
+		{template: "{raw_title}.{ext}", want: "Test video (Official Video).mp4"},
+		{template: "{upload_date} {source} {format_id}", want: "20091025 YouTube 137.mp4"},
+		{template: "{uploader}/{title}", want: "Uploader_Test video.mp4"},
+		{template: "{Title}", want: "{Title}.mp4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.template, func(t *testing.T) {
+			if got := templateFilename(test.template, media, format); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package www
+
+import (
+	"fmt"
+	"media-downloader/internal/media/ffmpeg"
+	"media-downloader/internal/media/ytdlp"
+	"net/http"
+)
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+
+	// Number of yt-dlp processes running right now, for monitoring load
+	YtdlpProcesses int `json:"ytdlp_processes"`
+
+	YtdlpVersion string `json:"ytdlp_version,omitempty"`
+}
+
+// Liveness only tells whether the process is up and serving requests
+func liveHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// Readiness tells whether yt-dlp runs and every dependency of the configured
+// features is present
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	version, err := ytdlp.CheckVersion(r.Context())
+	if err != nil {
+		writeHealth(w, http.StatusServiceUnavailable, healthResponse{
+			Status:         "not_ready",
+			Reason:         err.Error(),
+			YtdlpProcesses: ytdlp.InUse(),
+		})
+		return
+	}
+
+	if ffmpegFeatures {
+		if err := ffmpeg.Available(); err != nil {
+			writeHealth(w, http.StatusServiceUnavailable, healthResponse{
+				Status:         "not_ready",
+				Reason:         err.Error(),
+				YtdlpProcesses: ytdlp.InUse(),
+				YtdlpVersion:   version,
+			})
+			return
+		}
+	}
+
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ready", YtdlpProcesses: ytdlp.InUse(), YtdlpVersion: version})
+}
+
+func writeHealth(w http.ResponseWriter, status int, response healthResponse) {
+	jsonBytes, err := marshalJSON(response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
+	w.WriteHeader(status)
+	_, _ = w.Write(jsonBytes)
+}
@@ -0,0 +1,89 @@
+package www
+
+import (
+	"encoding/json"
+	"media-downloader/internal/media/ytdlp/ytdlptest"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadyHandlerFFmpeg(t *testing.T) {
+	tests := []struct {
+		name       string
+		features   bool
+		ffmpeg     bool
+		wantStatus int
+		wantState  string
+	}{
+		{
+			name:       "features enabled without ffmpeg",
+			features:   true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantState:  "not_ready",
+		},
+		{
+			name:       "features enabled with ffmpeg",
+			features:   true,
+			ffmpeg:     true,
+			wantStatus: http.StatusOK,
+			wantState:  "ready",
+		},
+		{
+			name:       "features disabled without ffmpeg",
+			features:   false,
+			wantStatus: http.StatusOK,
+			wantState:  "ready",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			previous := ffmpegFeatures
+			ffmpegFeatures = test.features
+			t.Cleanup(func() { ffmpegFeatures = previous })
+
+			// Only the PATH decides whether ffmpeg is found, yt-dlp always is
+			fakeYtdlp(t, ytdlptest.Replay("2024.08.06\n", "", nil))
+			dir := t.TempDir()
+			if test.ffmpeg {
+				if err := os.WriteFile(filepath.Join(dir, "ffmpeg"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+					t.Fatalf("failed to write fake ffmpeg: %v", err)
+				}
+			}
+			t.Setenv("PATH", dir)
+
+			recorder := httptest.NewRecorder()
+			readyHandler(recorder, httptest.NewRequest(http.MethodGet, "/api/health/ready", nil))
+
+			if recorder.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, test.wantStatus)
+			}
+			var response healthResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Status != test.wantState {
+				t.Errorf("got status %q, want %q", response.Status, test.wantState)
+			}
+			if response.YtdlpVersion != "2024.08.06" {
+				t.Errorf("got yt-dlp version %q", response.YtdlpVersion)
+			}
+		})
+	}
+}
+
+func TestLiveHandlerIgnoresDependencies(t *testing.T) {
+	previous := ffmpegFeatures
+	ffmpegFeatures = true
+	t.Cleanup(func() { ffmpegFeatures = previous })
+	t.Setenv("PATH", t.TempDir())
+
+	recorder := httptest.NewRecorder()
+	liveHandler(recorder, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", recorder.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,74 @@
+package www
+
+import (
+	"fmt"
+	"media-downloader/internal/media"
+	"media-downloader/internal/media/info"
+	"net/http"
+	"time"
+)
+
+// infoResponse is the media's metadata without any formats
+type infoResponse struct {
+	Url         string         `json:"url"`
+	Title       string         `json:"title"`
+	CleanTitle  string         `json:"clean_title,omitempty"`
+	Duration    float64        `json:"duration"`
+	Thumbnail   string         `json:"thumbnail"`
+	IsLive      bool           `json:"is_live"`
+	Uploader    string         `json:"uploader,omitempty"`
+	UploaderURL string         `json:"uploader_url,omitempty"`
+	UploadedAt  *time.Time     `json:"uploaded_at,omitempty"`
+	ViewCount   int64          `json:"view_count,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Chapters    []info.Chapter `json:"chapters"`
+}
+
+// infoHandler returns just the metadata of a media, skipping the slow
+// format availability checks of the quality endpoint
+func infoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := ParseQuery(r)
+	urlParam, err := query.Get("url")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing url parameter")
+		return
+	}
+
+	mediaInfo, err := media.FetchMetadata(requestContext(r, query), urlParam)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	jsonBytes, err := marshalJSON(infoResponse{
+		Url:         mediaInfo.Url,
+		Title:       mediaInfo.Title,
+		CleanTitle:  mediaInfo.CleanTitle,
+		Duration:    mediaInfo.Duration,
+		Thumbnail:   mediaInfo.Thumbnail,
+		IsLive:      mediaInfo.IsLive,
+		Uploader:    mediaInfo.Uploader,
+		UploaderURL: mediaInfo.UploaderURL,
+		UploadedAt:  mediaInfo.UploadedAt,
+		ViewCount:   mediaInfo.ViewCount,
+		Description: mediaInfo.Description,
+		Chapters:    mediaInfo.Chapters,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
+	_, err = w.Write(jsonBytes)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+}
@@ -0,0 +1,176 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"media-downloader/internal/jobs"
+	"media-downloader/internal/media"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/media/ytdlp"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Jobs waiting for a worker beyond this are refused
+const maxQueuedJobs = 100
+
+var jobManager *jobs.Manager
+
+// jobsHandler queues the download of a single format as a job, taking the
+// same parameters as a single format download in the URL or the body. The
+// file can be fetched from the job's result once it's done.
+func jobsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query, err := ParseForm(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid request body: "+err.Error())
+		return
+	}
+
+	urlParam, err := query.Get("url")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing url parameter")
+		return
+	}
+
+	if !query.Has("source") {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing source parameter")
+		return
+	}
+	source, err := query.GetSource("source")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid source parameter")
+		return
+	}
+
+	sourceIdentifier, err := query.Get("source_identifier")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing source_identifier parameter")
+		return
+	}
+
+	template := query.GetOrDefault("filename", filenameTemplate)
+	if err = validateFilenameTemplate(template); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid filename parameter: "+err.Error())
+		return
+	}
+
+	// The job outlives the request, only the cookies profile is carried over
+	profile := r.Header.Get("X-Cookies-Profile")
+	job, err := jobManager.Submit(func(ctx context.Context) (jobs.Result, error) {
+		ctx = ytdlp.WithCookiesProfile(ctx, profile)
+		mediaInfo, err := media.FetchMetadata(ctx, urlParam)
+		if err != nil {
+			return jobs.Result{}, err
+		}
+
+		path, err := media.DownloadToFile(ctx, urlParam, source, sourceIdentifier)
+		if err != nil {
+			return jobs.Result{}, err
+		}
+		extension := strings.TrimPrefix(filepath.Ext(path), ".")
+		format := &info.Format{SourceIdentifier: sourceIdentifier, Extension: extension}
+		return jobs.Result{Path: path, Filename: templateFilename(template, mediaInfo, format)}, nil
+	})
+	if errors.Is(err, jobs.ErrQueueFull) {
+		w.Header().Set("Retry-After", "60")
+		writeError(w, http.StatusServiceUnavailable, codeTemporarilyUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/api/jobs/"+job.ID())
+	writeJobStatus(w, http.StatusAccepted, job)
+}
+
+// jobHandler reports the state and progress of a job, or cancels it on DELETE
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		cancelJobHandler(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	job, ok := jobManager.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNotFound, "Unknown job, it may have expired")
+		return
+	}
+
+	writeJobStatus(w, http.StatusOK, job)
+}
+
+// cancelJobHandler stops a queued or running job, or discards a finished one
+// along with its file
+func cancelJobHandler(w http.ResponseWriter, r *http.Request) {
+	if !jobManager.Cancel(r.PathValue("id")) {
+		writeError(w, http.StatusNotFound, codeNotFound, "Unknown job, it may have expired")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// jobResultHandler serves the file of a finished job
+func jobResultHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	job, ok := jobManager.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNotFound, "Unknown job, it may have expired")
+		return
+	}
+
+	result, ok := job.Result()
+	if !ok {
+		writeError(w, http.StatusConflict, codeJobNotReady, fmt.Sprintf("The job is %s", job.Status().State))
+		return
+	}
+
+	file, err := os.Open(result.Path)
+	if err != nil {
+		writeError(w, http.StatusNotFound, codeNotFound, "The job's file is gone, it may have expired")
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", mimeForExt(filepath.Ext(result.Filename)))
+	w.Header().Set("Content-Disposition", contentDisposition(result.Filename))
+	http.ServeContent(w, r, result.Filename, stat.ModTime(), file)
+}
+
+func writeJobStatus(w http.ResponseWriter, status int, job *jobs.Job) {
+	jsonBytes, err := marshalJSON(job.Status())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
+	w.WriteHeader(status)
+	_, _ = w.Write(jsonBytes)
+}
@@ -0,0 +1,35 @@
+package www
+
+import (
+	"math"
+	"media-downloader/internal/ratelimit"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Limits how often a single client may hit the endpoints spawning processes
+var clientLimiter *ratelimit.Keyed
+
+// withClientLimit rejects requests from clients that are over their request
+// budget with 429 and a Retry-After header
+func withClientLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if wait := clientLimiter.Allow(clientIP(r)); wait > 0 {
+			seconds := max(1, int(math.Ceil(wait.Seconds())))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			writeError(w, http.StatusTooManyRequests, codeRateLimited, "Too many requests, try again later")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,78 @@
+package www
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+var logger = slog.Default()
+
+// SetLogger replaces the logger requests are logged to, a logger with a
+// discarding handler silences them
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush keeps server-sent events working through the wrapper
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// withLogging logs every request once it's done, along with the media URL
+// it was for and how long it took
+func withLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		// Handlers that never write still answer with 200
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		attrs := []any{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int64("bytes", recorder.bytes),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if url := r.URL.Query().Get("url"); url != "" {
+			attrs = append(attrs, slog.String("url", url))
+		}
+		logger.InfoContext(r.Context(), "request", attrs...)
+	})
+}
@@ -0,0 +1,21 @@
+package www
+
+import (
+	"media-downloader/internal/metrics"
+	"net/http"
+	"strconv"
+)
+
+// withMetrics counts the requests to an endpoint by their response status
+func withMetrics(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		next(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		metrics.Requests.WithLabelValues(endpoint, strconv.Itoa(status)).Inc()
+	}
+}
@@ -0,0 +1,30 @@
+package www
+
+import "strings"
+
+// Content types of the extensions downloads come in
+var mimeTypes = map[string]string{
+	"mp4":  "video/mp4",
+	"m4v":  "video/mp4",
+	"webm": "video/webm",
+	"mkv":  "video/x-matroska",
+	"mov":  "video/quicktime",
+	"3gp":  "video/3gpp",
+	"flv":  "video/x-flv",
+	"m4a":  "audio/mp4",
+	"mp3":  "audio/mpeg",
+	"aac":  "audio/aac",
+	"opus": "audio/ogg",
+	"ogg":  "audio/ogg",
+	"flac": "audio/flac",
+	"wav":  "audio/wav",
+}
+
+// mimeForExt returns the content type of a file extension, with or without
+// the leading dot, falling back to application/octet-stream
+func mimeForExt(ext string) string {
+	if mimeType, ok := mimeTypes[strings.ToLower(strings.TrimPrefix(ext, "."))]; ok {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,32 @@
+package www
+
+import "testing"
+
+func TestMimeForExt(t *testing.T) {
+	tests := []struct {
+		ext  string
+		want string
+	}{
+		{"mp4", "video/mp4"},
+		{"webm", "video/webm"},
+		{"mkv", "video/x-matroska"},
+		{"m4a", "audio/mp4"},
+		{"mp3", "audio/mpeg"},
+		{"opus", "audio/ogg"},
+		{"flac", "audio/flac"},
+		{".mp4", "video/mp4"},
+		{"MP3", "audio/mpeg"},
+		{".WebM", "video/webm"},
+		{"mhtml", "application/octet-stream"},
+		{"", "application/octet-stream"},
+		{"..mp4", "application/octet-stream"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ext, func(t *testing.T) {
+			if got := mimeForExt(test.ext); got != test.want {
+				t.Errorf("mimeForExt(%q) = %q, want %q", test.ext, got, test.want)
+			}
+		})
+	}
+}
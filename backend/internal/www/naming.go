@@ -0,0 +1,76 @@
+package www
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type JSONNaming int
+
+const (
+	SnakeCase JSONNaming = iota
+	CamelCase
+)
+
+func ParseJSONNaming(value string) (JSONNaming, error) {
+	switch strings.ToLower(strings.ReplaceAll(value, "_", "")) {
+	case "", "snakecase", "snake":
+		return SnakeCase, nil
+	case "camelcase", "camel":
+		return CamelCase, nil
+	default:
+		return SnakeCase, fmt.Errorf("unknown JSON naming %q", value)
+	}
+}
+
+var jsonNaming = SnakeCase
+
+// marshalJSON marshals v using its snake_case json tags, then renames every
+// object key when camelCase output is configured
+func marshalJSON(v any) ([]byte, error) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil || jsonNaming == SnakeCase {
+		return jsonBytes, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(renameKeys(generic, snakeToCamel))
+}
+
+func renameKeys(value any, rename func(string) string) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		renamed := make(map[string]any, len(typed))
+		for key, item := range typed {
+			renamed[rename(key)] = renameKeys(item, rename)
+		}
+		return renamed
+	case []any:
+		for i, item := range typed {
+			typed[i] = renameKeys(item, rename)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+func snakeToCamel(key string) string {
+	parts := strings.Split(key, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] == "" {
+			continue
+		}
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,84 @@
+package www
+
+import (
+	"encoding/json"
+	"media-downloader/internal/media/info"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSONNaming(t *testing.T) {
+	media := info.Media{
+		Title:    "Test video",
+		Duration: 212,
+		VideoFormats: []info.VideoFormat{{
+			VideoCodec:  "avc1.640028",
+			VideoHeight: 1080,
+			Format:      info.Format{SourceIdentifier: "137", Size: 9007199254740993},
+		}},
+	}
+
+	tests := []struct {
+		naming JSONNaming
+		keys   []string
+		format []string
+		size   string
+	}{
+		{SnakeCase, []string{"video_formats", "audio_formats", "duration", "is_live"}, []string{"video_codec", "video_height", "source_identifier"}, `"size":9007199254740993`},
+		{CamelCase, []string{"videoFormats", "audioFormats", "duration", "isLive"}, []string{"videoCodec", "videoHeight", "sourceIdentifier"}, `"size":9007199254740993`},
+	}
+
+	for _, test := range tests {
+		jsonNaming = test.naming
+		jsonBytes, err := marshalJSON(media)
+		jsonNaming = SnakeCase
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var fields map[string]json.RawMessage
+		var formats []map[string]json.RawMessage
+		if err = json.Unmarshal(jsonBytes, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Unmarshal(fields[test.keys[0]], &formats); err != nil || len(formats) != 1 {
+			t.Fatalf("%q isn't a list of one format: %v", test.keys[0], err)
+		}
+
+		for _, key := range test.keys {
+			if _, ok := fields[key]; !ok {
+				t.Errorf("naming %d: missing key %q in %s", test.naming, key, jsonBytes)
+			}
+		}
+		for _, key := range test.format {
+			if _, ok := formats[0][key]; !ok {
+				t.Errorf("naming %d: missing format key %q in %s", test.naming, key, jsonBytes)
+			}
+		}
+		// Renaming must not round large numbers through float64
+		if !strings.Contains(string(jsonBytes), test.size) {
+			t.Errorf("naming %d: %s doesn't contain %s", test.naming, jsonBytes, test.size)
+		}
+	}
+}
+
+func TestParseJSONNaming(t *testing.T) {
+	tests := []struct {
+		value string
+		want  JSONNaming
+		ok    bool
+	}{
+		{"", SnakeCase, true},
+		{"snake_case", SnakeCase, true},
+		{"camelCase", CamelCase, true},
+		{"CAMEL", CamelCase, true},
+		{"kebab-case", SnakeCase, false},
+	}
+
+	for _, test := range tests {
+		got, err := ParseJSONNaming(test.value)
+		if got != test.want || (err == nil) != test.ok {
+			t.Errorf("ParseJSONNaming(%q) = %d, %v", test.value, got, err)
+		}
+	}
+}
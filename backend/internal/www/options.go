@@ -0,0 +1,45 @@
+package www
+
+import (
+	"media-downloader/internal/media"
+	"net/http"
+	"regexp"
+)
+
+var (
+	countryPattern    = regexp.MustCompile(`^[A-Za-z]{2}$`)
+	formatSortPattern = regexp.MustCompile(`^[A-Za-z0-9_,:+.~-]{1,200}$`)
+)
+
+// fetchOptions turns the extraction parameters clients may set into options,
+// writing the error response when they are invalid. Options that could reach
+// other hosts, like a proxy, are left to the server's configuration
+func fetchOptions(w http.ResponseWriter, query RequestQuery) ([]media.FetchOption, bool) {
+	var opts []media.FetchOption
+
+	if country, err := query.Get("geo_bypass_country"); err == nil {
+		if !countryPattern.MatchString(country) {
+			writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid geo_bypass_country parameter, expected a two letter country code")
+			return nil, false
+		}
+		opts = append(opts, media.WithGeoBypassCountry(country))
+	}
+
+	if sort, err := query.Get("format_sort"); err == nil {
+		if !formatSortPattern.MatchString(sort) {
+			writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid format_sort parameter")
+			return nil, false
+		}
+		opts = append(opts, media.WithFormatSort(sort))
+	}
+
+	if storyboards, _ := query.GetBool("storyboards"); storyboards {
+		opts = append(opts, media.WithStoryboards())
+	}
+
+	if fast, _ := query.GetBool("fast"); fast {
+		opts = append(opts, media.WithoutFormatCheck())
+	}
+
+	return opts, true
+}
@@ -0,0 +1,104 @@
+package www
+
+import (
+	"fmt"
+	"media-downloader/internal/media"
+	"media-downloader/internal/media/info"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+func playlistHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := ParseQuery(r)
+	urlParam, err := query.Get("url")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing url parameter")
+		return
+	}
+
+	if acceptsNDJSON(r) {
+		streamPlaylist(w, r, urlParam)
+		return
+	}
+
+	entries := make([]info.PlaylistEntry, 0)
+	err = media.StreamPlaylist(r.Context(), urlParam, func(entry info.PlaylistEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	jsonBytes, err := marshalJSON(entries)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
+	_, err = w.Write(jsonBytes)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+}
+
+// streamPlaylist writes each entry as its own JSON line as soon as it's known
+func streamPlaylist(w http.ResponseWriter, r *http.Request, urlParam string) {
+	flusher, _ := w.(http.Flusher)
+	started := false
+
+	err := media.StreamPlaylist(r.Context(), urlParam, func(entry info.PlaylistEntry) error {
+		jsonBytes, err := marshalJSON(entry)
+		if err != nil {
+			return err
+		}
+
+		if !started {
+			w.Header().Set("Content-Type", ndjsonContentType)
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+
+		if _, err = w.Write(append(jsonBytes, '\n')); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	// Once the first line is out the status can't change anymore
+	if err != nil && !started {
+		writeMediaError(w, err)
+		return
+	}
+
+	// An empty playlist is still a successful stream
+	if !started {
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == ndjsonContentType {
+			return true
+		}
+	}
+	return false
+}
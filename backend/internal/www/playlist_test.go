@@ -0,0 +1,110 @@
+package www
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/media/ytdlp"
+	"media-downloader/internal/media/ytdlp/ytdlptest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeYtdlp runs process in place of yt-dlp for the rest of the test
+func fakeYtdlp(t *testing.T, process ytdlptest.Process) {
+	t.Helper()
+	ytdlp.SetRunner(ytdlptest.New(process))
+
+	// Every test fakes different output for the same URL
+	ytdlp.SetCache(0, 0, 0)
+	t.Cleanup(func() {
+		ytdlp.SetRunner(nil)
+		ytdlp.SetCache(5*time.Minute, 256, 0)
+	})
+}
+
+func TestPlaylistStreamsEntriesIncrementally(t *testing.T) {
+	// Each entry is only printed once the previous one was released
+	entries := strings.SplitAfter(strings.TrimSpace(ytdlptest.Fixture(t, "youtube_playlist.jsonl")), "\n")
+	released := make(chan struct{}, len(entries))
+	fakeYtdlp(t, func(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+		for i, entry := range entries {
+			if i > 0 {
+				select {
+				case <-released:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if _, err := io.WriteString(stdout, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(playlistHandler))
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/api/playlist?url=https://www.youtube.com/playlist?list%3DPLtest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Accept", ndjsonContentType)
+	// Without flushing not even the headers would arrive
+	client := &http.Client{Timeout: 10 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if got := response.Header.Get("Content-Type"); got != ndjsonContentType {
+		t.Fatalf("got content type %q, want %q", got, ndjsonContentType)
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	// yt-dlp holds back each entry until the previous one arrived, so a
+	// buffered response would never get past the first
+	want := []string{
+		"Rick Astley - Never Gonna Give You Up (Official Music Video)",
+		"Rick Astley - Together Forever (Official Video) [Remastered in 4K]",
+		"Rick Astley - Whenever You Need Somebody (Official Video) [Remastered in 4K]",
+	}
+	for i, title := range want {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream ended after %d entries", i)
+			}
+			var entry info.PlaylistEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("line %q isn't an entry: %v", line, err)
+			}
+			if entry.Title != title {
+				t.Errorf("entry %d has title %q, want %q", i, entry.Title, title)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("entry %d never arrived, the stream isn't flushed per entry", i)
+		}
+
+		released <- struct{}{}
+	}
+
+	if line, ok := <-lines; ok {
+		t.Errorf("unexpected line after the last entry: %q", line)
+	}
+}
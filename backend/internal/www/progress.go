@@ -0,0 +1,123 @@
+package www
+
+import (
+	"fmt"
+	"media-downloader/internal/progress"
+	"net/http"
+	"time"
+)
+
+const progressInterval = 250 * time.Millisecond
+
+var downloadProgress = progress.NewRegistry()
+
+type progressIDResponse struct {
+	ID string `json:"id"`
+}
+
+// downloadProgressHandler hands out progress IDs on POST. A download given
+// one as its progress_id can then be followed with GET, streaming its
+// progress as server-sent events until it's done or the client leaves.
+func downloadProgressHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		createProgressHandler(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := ParseQuery(r)
+	id, err := query.Get("id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing id parameter")
+		return
+	}
+
+	tracker, ok := downloadProgress.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNotFound, "Unknown progress id")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, codeInternal, "Streaming unsupported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	var last progress.Snapshot
+	first := true
+	for {
+		snapshot := tracker.Snapshot()
+		if first || snapshot != last {
+			jsonBytes, err := marshalJSON(snapshot)
+			if err != nil {
+				return
+			}
+
+			event := "progress"
+			if snapshot.Done {
+				event = "done"
+			}
+			if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, jsonBytes); err != nil {
+				return
+			}
+			flusher.Flush()
+
+			first = false
+			last = snapshot
+		}
+
+		if snapshot.Done {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func createProgressHandler(w http.ResponseWriter, r *http.Request) {
+	id, _, err := downloadProgress.Create()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	jsonBytes, err := marshalJSON(progressIDResponse{ID: id})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(jsonBytes)
+}
+
+// progressTracker returns the tracker for the request's progress_id, if any.
+// IDs the server didn't hand out are ignored.
+func progressTracker(r *http.Request) *progress.Tracker {
+	id := r.URL.Query().Get("progress_id")
+	if id == "" {
+		return nil
+	}
+	tracker, _ := downloadProgress.Get(id)
+	return tracker
+}
@@ -1,17 +1,83 @@
 package www
 
 import (
+	"encoding/json"
 	"fmt"
+	"media-downloader/internal/media/sources"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
+// Largest form or JSON body ParseForm reads
+const maxFormBodyBytes = 1 << 20
+
 type RequestQuery map[string][]string
 
 func ParseQuery(r *http.Request) RequestQuery {
 	return RequestQuery(r.URL.Query())
 }
 
+// ParseForm is ParseQuery for requests that may carry their parameters in
+// the body, either form encoded or as a flat JSON object. Body parameters
+// come before those of the URL, so they win in Get.
+func ParseForm(r *http.Request) (RequestQuery, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, maxFormBodyBytes)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		if err := r.ParseForm(); err != nil {
+			return nil, fmt.Errorf("parse form: %w", err)
+		}
+		return RequestQuery(r.Form), nil
+	}
+
+	body, err := parseJSONBody(r)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.URL.Query() {
+		body[key] = append(body[key], values...)
+	}
+	return body, nil
+}
+
+// parseJSONBody reads a JSON object of strings, numbers, booleans and arrays
+// of those as parameters, an array giving a parameter several values
+func parseJSONBody(r *http.Request) (RequestQuery, error) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("parse JSON body: %w", err)
+	}
+
+	query := RequestQuery{}
+	for key, value := range object {
+		values, ok := value.([]any)
+		if !ok {
+			values = []any{value}
+		}
+
+		for _, value := range values {
+			switch value := value.(type) {
+			case nil:
+			case string:
+				query[key] = append(query[key], value)
+			case json.Number:
+				query[key] = append(query[key], value.String())
+			case bool:
+				query[key] = append(query[key], strconv.FormatBool(value))
+			default:
+				return nil, fmt.Errorf("parse JSON body: %s must be a string, number, boolean or an array of those", key)
+			}
+		}
+	}
+	return query, nil
+}
+
 func (q RequestQuery) Has(key string) bool {
 	_, ok := q[key]
 	return ok
@@ -70,3 +136,53 @@ func (q RequestQuery) GetFloat64(key string) (float64, error) {
 
 	return floatValue, nil
 }
+
+// GetSource accepts either a source's numeric value or its name, such as "youtube"
+func (q RequestQuery) GetSource(key string) (sources.Source, error) {
+	value, err := q.Get(key)
+	if err != nil {
+		return sources.Unknown, err
+	}
+
+	if number, err := strconv.Atoi(value); err == nil {
+		return sources.Source(number), nil
+	}
+	if source, ok := sources.FromString(strings.TrimSpace(value)); ok {
+		return source, nil
+	}
+	return sources.Unknown, fmt.Errorf("key %q has unknown source %q", key, value)
+}
+
+func (q RequestQuery) GetBool(key string) (bool, error) {
+	value, err := q.Get(key)
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes":
+		return true, nil
+	case "0", "false", "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("key %q has invalid boolean value %q", key, value)
+	}
+}
+
+func (q RequestQuery) GetOrDefault(key string, def string) string {
+	value, err := q.Get(key)
+	if err != nil {
+		return def
+	}
+
+	return value
+}
+
+func (q RequestQuery) GetIntOrDefault(key string, def int) int {
+	value, err := q.GetInt(key)
+	if err != nil {
+		return def
+	}
+
+	return value
+}
@@ -0,0 +1,77 @@
+package www
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseForm(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        RequestQuery
+		wantErr     bool
+	}{
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "url=https%3A%2F%2Fyoutu.be%2FdQw4w9WgXcQ&quality=720",
+			want:        RequestQuery{"url": {"https://youtu.be/dQw4w9WgXcQ", "https://example.com"}, "quality": {"720"}},
+		},
+		{
+			name:        "json",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"url": "https://youtu.be/dQw4w9WgXcQ", "max_height": 720, "fast": true, "lang": ["en", "de"], "source": null}`,
+			want: RequestQuery{
+				"url":        {"https://youtu.be/dQw4w9WgXcQ", "https://example.com"},
+				"max_height": {"720"},
+				"fast":       {"true"},
+				"lang":       {"en", "de"},
+			},
+		},
+		{
+			name:        "nested json",
+			contentType: "application/json",
+			body:        `{"options": {"fast": true}}`,
+			wantErr:     true,
+		},
+		{
+			name:        "invalid json",
+			contentType: "application/json",
+			body:        `{"url": `,
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The body's parameters come before the URL's
+			request := httptest.NewRequest(http.MethodPost, "/api/jobs?url=https://example.com", strings.NewReader(test.body))
+			request.Header.Set("Content-Type", test.contentType)
+
+			query, err := ParseForm(request)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(query) != len(test.want) {
+				t.Errorf("got %v, want %v", query, test.want)
+			}
+			for key, want := range test.want {
+				if got := query[key]; !slices.Equal(got, want) {
+					t.Errorf("got %s %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package www
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/ratelimit"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// byteRange is a single range of a download, end included
+type byteRange struct {
+	start int64
+	end   int64
+	size  int64
+}
+
+// exactSize returns the size of a download if it's known to the byte. Clips
+// and approximate sizes aren't, so ranges can't be served for them.
+func exactSize(r *http.Request, format *info.Format) (int64, bool) {
+	query := ParseQuery(r)
+	if format.Size == 0 || format.SizeApproximate || query.Has("start") || query.Has("end") {
+		return 0, false
+	}
+	return int64(format.Size), true
+}
+
+// requestedRange parses the Range header of a download of an exact size.
+// Only a single range is served, anything else is ignored and the whole
+// download sent instead, as RFC 9110 allows. There's no validator to check
+// If-Range against, so a Range with one is ignored too.
+func requestedRange(r *http.Request, size int64) (byteRange, bool, error) {
+	header := r.Header.Get("Range")
+	if header == "" || r.Header.Get("If-Range") != "" {
+		return byteRange{}, false, nil
+	}
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return byteRange{}, false, nil
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return byteRange{}, false, nil
+	}
+
+	span := byteRange{end: size - 1, size: size}
+	if first == "" {
+		// A suffix range, the last bytes of the download
+		length, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || length < 0 {
+			return byteRange{}, false, nil
+		}
+		if length == 0 {
+			return byteRange{}, false, errRangeNotSatisfiable
+		}
+		span.start = max(size-length, 0)
+		return span, true, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return byteRange{}, false, nil
+	}
+	if last != "" {
+		end, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return byteRange{}, false, nil
+		}
+		span.end = min(end, size-1)
+	}
+	if start >= size {
+		return byteRange{}, false, errRangeNotSatisfiable
+	}
+	span.start = start
+	return span, true, nil
+}
+
+// serveRange streams a range of a download that can't seek by skipping the
+// bytes before it. The status is only sent once the range's first byte has
+// arrived, so a failure before that can still be answered with an error.
+func serveRange(w http.ResponseWriter, r *http.Request, source io.Reader, span byteRange) (int64, error) {
+	if _, err := io.CopyN(io.Discard, source, span.start); err != nil {
+		return 0, fmt.Errorf("failed to skip to the range: %w", err)
+	}
+	buffered := bufio.NewReader(source)
+	if _, err := buffered.Peek(1); err != nil {
+		return 0, fmt.Errorf("failed to read the range: %w", err)
+	}
+
+	length := span.end - span.start + 1
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", span.start, span.end, span.size))
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	w.WriteHeader(http.StatusPartialContent)
+	return io.CopyN(w, ratelimit.NewReader(r.Context(), buffered, downloadBucket), length)
+}
@@ -0,0 +1,53 @@
+package www
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestedRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		ifRange string
+		want    byteRange
+		wantOK  bool
+		wantErr error
+	}{
+		{name: "no range"},
+		{name: "closed range", header: "bytes=2-4", want: byteRange{start: 2, end: 4, size: 10}, wantOK: true},
+		{name: "open range", header: "bytes=5-", want: byteRange{start: 5, end: 9, size: 10}, wantOK: true},
+		{name: "end past the size", header: "bytes=5-100", want: byteRange{start: 5, end: 9, size: 10}, wantOK: true},
+		{name: "suffix", header: "bytes=-3", want: byteRange{start: 7, end: 9, size: 10}, wantOK: true},
+		{name: "suffix longer than the size", header: "bytes=-30", want: byteRange{start: 0, end: 9, size: 10}, wantOK: true},
+		{name: "start past the size", header: "bytes=10-", wantErr: errRangeNotSatisfiable},
+		{name: "empty suffix", header: "bytes=-0", wantErr: errRangeNotSatisfiable},
+		{name: "multiple ranges", header: "bytes=0-1,4-5"},
+		{name: "other unit", header: "items=0-1"},
+		{name: "end before start", header: "bytes=4-2"},
+		{name: "malformed", header: "bytes=a-b"},
+		{name: "with if-range", header: "bytes=2-4", ifRange: `"etag"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/download", nil)
+			if test.header != "" {
+				request.Header.Set("Range", test.header)
+			}
+			if test.ifRange != "" {
+				request.Header.Set("If-Range", test.ifRange)
+			}
+
+			got, ok, err := requestedRange(request, 10)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("got %+v, %v, want %+v, %v", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
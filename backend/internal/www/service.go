@@ -1,96 +1,568 @@
 package www
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"media-downloader/internal/config"
+	"media-downloader/internal/jobs"
 	"media-downloader/internal/media"
+	"media-downloader/internal/media/info"
 	"media-downloader/internal/media/sources"
+	"media-downloader/internal/media/ytdlp"
+	"media-downloader/internal/metrics"
+	"media-downloader/internal/progress"
+	"media-downloader/internal/ratelimit"
 	"net/http"
+	"strings"
 )
 
-func Initialize() error {
-	http.HandleFunc("/api/quality", qualityHandler)
-	http.HandleFunc("/api/download", downloadHandler)
-	return http.ListenAndServe(":8080", nil)
+// Shared by every download so the aggregate egress stays under the configured cap
+var downloadBucket *ratelimit.Bucket
+
+var ffmpegFeatures bool
+
+// NewServer applies the configuration and returns a server for all API routes,
+// ready to be started with ListenAndServe and stopped with Shutdown
+func NewServer(cfg *config.Config) (*http.Server, error) {
+	downloadBucket = ratelimit.NewBucket(cfg.MaxDownloadRate)
+	ffmpegFeatures = cfg.FFmpegFeatures
+	allowedOrigins = ParseOrigins(cfg.CORSOrigins)
+	clientLimiter = ratelimit.NewKeyed(cfg.ClientRateLimit, cfg.ClientRateBurst)
+	maxDownloadBytes = uint64(cfg.MaxDownloadBytes)
+	jobManager = jobs.NewManager(int(cfg.JobWorkers), maxQueuedJobs, cfg.JobResultTTL)
+
+	naming, err := ParseJSONNaming(cfg.JSONNaming)
+	if err != nil {
+		return nil, err
+	}
+	jsonNaming = naming
+
+	if err = validateFilenameTemplate(cfg.FilenameTemplate); err != nil {
+		return nil, err
+	}
+	filenameTemplate = cfg.FilenameTemplate
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/quality", withMetrics("quality", withClientLimit(qualityHandler)))
+	mux.HandleFunc("/api/quality/batch", withMetrics("quality_batch", withClientLimit(qualityBatchHandler)))
+	mux.HandleFunc("/api/quality/merged_size", withClientLimit(mergedSizeHandler))
+	mux.HandleFunc("/api/info", withClientLimit(infoHandler))
+	mux.HandleFunc("/api/download", withMetrics("download", withClientLimit(downloadHandler)))
+	mux.HandleFunc("/api/download/progress", downloadProgressHandler)
+	mux.HandleFunc("/api/jobs", withClientLimit(jobsHandler))
+	mux.HandleFunc("/api/jobs/{id}", jobHandler)
+	mux.HandleFunc("/api/jobs/{id}/result", jobResultHandler)
+	mux.HandleFunc("/api/sources", sourcesHandler)
+	mux.HandleFunc("/api/playlist", withClientLimit(playlistHandler))
+	mux.HandleFunc("/api/subtitles", withClientLimit(subtitlesHandler))
+	mux.HandleFunc("/api/health", liveHandler)
+	mux.HandleFunc("/api/health/ready", readyHandler)
+	// The names container orchestrators conventionally probe
+	mux.HandleFunc("/healthz", liveHandler)
+	mux.HandleFunc("/readyz", readyHandler)
+	mux.Handle("/metrics", metrics.Handler())
+	if cfg.DebugEndpoints {
+		mux.HandleFunc("/api/debug/raw", withClientLimit(debugRawHandler))
+	}
+
+	return &http.Server{
+		Addr:    cfg.ListenAddr,
+		Handler: withLogging(withCORS(withCompression(mux))),
+	}, nil
+}
+
+// Initialize serves the API until ctx is done, then shuts down gracefully,
+// giving in-flight requests up to the configured timeout to finish
+func Initialize(ctx context.Context, cfg *config.Config) error {
+	server, err := NewServer(cfg)
+	if err != nil {
+		return err
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+	// Jobs can't be resumed after a restart, so their files go too
+	jobManager.Stop()
+	if err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	return nil
 }
 
 func qualityHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	query := ParseQuery(r)
 	urlParam, err := query.Get("url")
 	if err != nil {
-		http.Error(w, "Missing url parameter", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing url parameter")
+		return
+	}
+
+	maxHeight := query.GetIntOrDefault("max_height", 0)
+	if query.Has("max_height") && maxHeight <= 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid max_height parameter")
+		return
+	}
+
+	opts, ok := fetchOptions(w, query)
+	if !ok {
+		return
+	}
+
+	mediaInfo, err := media.FetchMedia(requestContext(r, query), urlParam, opts...)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	// Live formats only cover what has been broadcast so far
+	if allowLive, _ := query.GetBool("allow_live"); mediaInfo.IsLive && !allowLive {
+		writeMediaError(w, info.ErrLiveStream)
+		return
+	}
+	mediaInfo.CleanFormats()
+
+	if maxHeight > 0 {
+		mediaInfo.FilterByMaxHeight(maxHeight)
+	}
+
+	mediaInfo.AvailableAudioLanguages = mediaInfo.AudioLanguages()
+	if audioLanguage := query.GetOrDefault("audio_lang", ""); audioLanguage != "" {
+		mediaInfo.FilterByAudioLanguage(audioLanguage)
+	}
+
+	if exactSize, _ := query.GetBool("exact_size"); exactSize {
+		media.ProbeExactSizes(r.Context(), mediaInfo)
+	}
+	mediaInfo.SortFormats()
+
+	// Codec priority, either comma separated or as repeated parameters
+	if codecs, err := query.GetAll("video_codec"); err == nil {
+		var order []string
+		for _, codec := range codecs {
+			order = append(order, strings.Split(codec, ",")...)
+		}
+		mediaInfo.PreferVideoCodec(order)
+	}
+
+	// Filter stats are only reported when debugging
+	if debug, _ := query.GetBool("debug"); !debug {
+		mediaInfo.FilterStats = nil
+	}
+
+	jsonBytes, err := marshalJSON(mediaInfo)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	// Polling clients only get the body again once it changed
+	if notModified(w, r, jsonBytes, ytdlp.CachedFor(requestContext(r, query), urlParam, opts...)) {
 		return
 	}
 
-	media, err := media.FetchMedia(r.Context(), urlParam)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
+	_, err = w.Write(jsonBytes)
 	if err != nil {
-		http.Error(w, "Failed to fetch video info", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
-	media.CleanFormats()
-	media.SortFormats()
+}
 
-	jsonBytes, err := json.Marshal(media)
+type sourceResponse struct {
+	ID          sources.Source `json:"id"`
+	Name        string         `json:"name"`
+	IsAudioOnly bool           `json:"is_audio_only"`
+}
+
+func sourcesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	response := make([]sourceResponse, 0)
+	for _, source := range sources.All() {
+		response = append(response, sourceResponse{
+			ID:          source,
+			Name:        source.String(),
+			IsAudioOnly: sources.IsAudioOnly(source),
+		})
+	}
+
+	jsonBytes, err := marshalJSON(response)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
 	_, err = w.Write(jsonBytes)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 }
 
 func downloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodHead {
+		downloadHeadHandler(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
+	// Make sure progress subscribers learn about downloads that never start
+	if tracker := progressTracker(r); tracker != nil {
+		defer tracker.Finish(errors.New("download failed"))
+	}
+
 	query := ParseQuery(r)
 	urlParam, err := query.Get("url")
 	if err != nil {
-		http.Error(w, "Missing url parameter", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing url parameter")
+		return
+	}
+
+	// Extract audio, either a specific format or a language track, transcoded
+	if audioOnly, _ := query.GetBool("audio_only"); audioOnly {
+		audioTrackHandler(w, r, query, urlParam)
+		return
+	}
+
+	// Let the server pick the best format, merging when there's no single one
+	if query.Has("quality") {
+		bestHandler(w, r, query, urlParam)
 		return
 	}
 
-	source, err := query.GetInt("source")
+	// Mux a separate video and audio format into a single file
+	if query.Has("video_identifier") || query.Has("audio_identifier") {
+		mergedHandler(w, r, query, urlParam)
+		return
+	}
+
+	if !query.Has("source") {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing source parameter")
+		return
+	}
+	source, err := query.GetSource("source")
 	if err != nil {
-		http.Error(w, "Missing source parameter", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid source parameter")
 		return
 	}
 
 	sourceIdentifier, err := query.Get("source_identifier")
 	if err != nil {
-		http.Error(w, "Missing source_identifier parameter", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing source_identifier parameter")
+		return
+	}
+
+	ctx, ok := downloadContext(w, r, query)
+	if !ok {
+		return
+	}
+
+	media, format, reader, err := media.DownloadMedia(ctx, urlParam, source, sourceIdentifier)
+	if err != nil {
+		writeMediaError(w, err)
 		return
 	}
 
-	media, format, reader, err := media.DownloadMedia(r.Context(), urlParam, sources.Source(source), sourceIdentifier)
+	writeDownload(w, r, media, format, reader)
+}
+
+// downloadHeadHandler answers with the headers a download would get, letting
+// clients learn the filename and size up front. Only single formats have a
+// size known before downloading, merged and transcoded output doesn't
+func downloadHeadHandler(w http.ResponseWriter, r *http.Request) {
+	query := ParseQuery(r)
+	urlParam, err := query.Get("url")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing url parameter")
+		return
+	}
+
+	audioOnly, _ := query.GetBool("audio_only")
+	if audioOnly || query.Has("quality") || query.Has("video_identifier") || query.Has("audio_identifier") {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "HEAD is only supported for single format downloads")
 		return
 	}
 
-	filename := fmt.Sprintf("%s.%s", media.Title, format.Extension)
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	if !query.Has("source") {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing source parameter")
+		return
+	}
+	source, err := query.GetSource("source")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid source parameter")
+		return
+	}
 
-	_, err = io.Copy(w, reader)
+	sourceIdentifier, err := query.Get("source_identifier")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing source_identifier parameter")
 		return
 	}
+
+	ctx, ok := downloadContext(w, r, query)
+	if !ok {
+		return
+	}
+
+	media, format, err := media.ResolveMedia(ctx, urlParam, source, sourceIdentifier)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	setDownloadHeaders(w, r, media, format)
+	if size, ok := exactSize(r, format); ok {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", size))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// bestHandler serves the best format with both video and audio, merging
+// separate streams only while ffmpeg features are enabled
+func bestHandler(w http.ResponseWriter, r *http.Request, query RequestQuery, urlParam string) {
+	if quality := query.GetOrDefault("quality", ""); quality != "best" {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "The quality parameter must be best")
+		return
+	}
+
+	ctx, ok := downloadContext(w, r, query)
+	if !ok {
+		return
+	}
+
+	media, format, reader, err := media.DownloadBest(ctx, urlParam, ffmpegFeatures)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	writeDownload(w, r, media, format, reader)
+}
+
+func mergedHandler(w http.ResponseWriter, r *http.Request, query RequestQuery, urlParam string) {
+	if !ffmpegFeatures {
+		writeError(w, http.StatusNotImplemented, codeFeatureDisabled, "Merging is disabled")
+		return
+	}
+
+	videoIdentifier, err := query.Get("video_identifier")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing video_identifier parameter")
+		return
+	}
+
+	audioIdentifier, err := query.Get("audio_identifier")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing audio_identifier parameter")
+		return
+	}
+
+	ctx, ok := downloadContext(w, r, query)
+	if !ok {
+		return
+	}
+
+	media, format, reader, err := media.DownloadMerged(ctx, urlParam, videoIdentifier, audioIdentifier)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	writeDownload(w, r, media, format, reader)
+}
+
+func audioTrackHandler(w http.ResponseWriter, r *http.Request, query RequestQuery, urlParam string) {
+	if !ffmpegFeatures {
+		writeError(w, http.StatusNotImplemented, codeFeatureDisabled, "Audio extraction is disabled")
+		return
+	}
+
+	audioFormat, err := query.Get("audio_ext")
+	if err != nil {
+		if audioFormat, err = query.Get("audio_format"); err != nil {
+			writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing audio_ext parameter")
+			return
+		}
+	}
+
+	ctx, ok := downloadContext(w, r, query)
+	if !ok {
+		return
+	}
+
+	// A specific audio format takes precedence over picking one by language
+	if sourceIdentifier, err := query.Get("source_identifier"); err == nil {
+		media, format, reader, err := media.ExtractAudio(ctx, urlParam, sourceIdentifier, audioFormat)
+		if err != nil {
+			writeMediaError(w, err)
+			return
+		}
+
+		writeDownload(w, r, media, format, reader)
+		return
+	}
+
+	// Without an explicit language the lang preference picks the track
+	language := query.GetOrDefault("language", "")
+	if language == "" && !query.Has("lang") {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing language parameter")
+		return
+	}
+
+	media, format, reader, err := media.ExtractAudioTrack(ctx, urlParam, language, audioFormat)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	writeDownload(w, r, media, format, reader)
+}
+
+func writeDownload(w http.ResponseWriter, r *http.Request, media *info.Media, format *info.Format, reader io.ReadCloser) {
+	defer reader.Close()
+
+	setDownloadHeaders(w, r, media, format)
+
+	// Without an exact size the Range header is ignored and the whole
+	// download sent, a stream that can't seek is never buffered for it
+	var span byteRange
+	ranged := false
+	if size, ok := exactSize(r, format); ok {
+		var err error
+		span, ranged, err = requestedRange(r, size)
+		if err != nil {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			writeError(w, http.StatusRequestedRangeNotSatisfiable, codeRangeNotSatisfiable, "The requested range is outside of the download")
+			return
+		}
+	}
+
+	var source io.Reader = reader
+	tracker := progressTracker(r)
+	if tracker != nil {
+		tracker.SetTotal(format.Size)
+		source = progress.NewReader(reader, tracker)
+	}
+	source = limitDownloadSize(source)
+
+	var err error
+	var written int64
+	if ranged {
+		written, err = serveRange(w, r, source, span)
+	} else {
+		written, err = io.Copy(w, ratelimit.NewReader(r.Context(), source, downloadBucket))
+	}
+	// yt-dlp may fail after its output ended, only Close tells. A range that
+	// ends early cuts it off, which isn't a failure.
+	if err == nil && (!ranged || span.end == span.size-1) {
+		err = reader.Close()
+	}
+	if tracker != nil {
+		tracker.Finish(err)
+	}
+	if err == nil {
+		return
+	}
+
+	if written > 0 {
+		// Once streaming has begun the status can't change anymore, cutting
+		// the connection keeps clients from keeping a truncated file
+		logger.WarnContext(r.Context(), "download aborted", "url", media.Url, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	if errors.Is(err, errDownloadTooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, codeTooLarge, err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+}
+
+// setDownloadHeaders sets the headers shared by downloads and their HEAD
+// requests. The filename parameter, validated by downloadContext, overrides
+// the configured filename template. Ranges are only offered for downloads
+// of an exact size.
+func setDownloadHeaders(w http.ResponseWriter, r *http.Request, media *info.Media, format *info.Format) {
+	template := ParseQuery(r).GetOrDefault("filename", filenameTemplate)
+	filename := templateFilename(template, media, format)
+	w.Header().Set("Content-Type", mimeForExt(format.Extension))
+	w.Header().Set("Content-Disposition", contentDisposition(filename))
+	if _, ok := exactSize(r, format); ok {
+		w.Header().Set("Accept-Ranges", "bytes")
+	} else {
+		w.Header().Set("Accept-Ranges", "none")
+	}
+}
+
+// downloadContext is the requestContext of a download, limited to the
+// section between the start and end parameters if given. It writes the
+// error response when those are invalid.
+func downloadContext(w http.ResponseWriter, r *http.Request, query RequestQuery) (context.Context, bool) {
+	if template, err := query.Get("filename"); err == nil {
+		if err = validateFilenameTemplate(template); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid filename parameter: "+err.Error())
+			return nil, false
+		}
+	}
+
+	ctx := requestContext(r, query)
+	if !query.Has("start") && !query.Has("end") {
+		return ctx, true
+	}
+
+	start, err := query.GetFloat64("start")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid start parameter")
+		return nil, false
+	}
+	end, err := query.GetFloat64("end")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "Invalid end parameter")
+		return nil, false
+	}
+	if start < 0 || start >= end {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "The start parameter must be before end")
+		return nil, false
+	}
+
+	return media.WithClip(ctx, media.Clip{Start: start, End: end}), true
+}
+
+// requestContext carries the request's preferred language, progress tracker
+// and cookies profile, if any, down to the media package. The profile comes
+// from a header so it doesn't end up in logs and browser history
+func requestContext(r *http.Request, query RequestQuery) context.Context {
+	language := query.GetOrDefault("lang", "")
+	ctx := media.WithLanguage(r.Context(), language)
+	ctx = ytdlp.WithCookiesProfile(ctx, r.Header.Get("X-Cookies-Profile"))
+	return progress.WithTracker(ctx, progressTracker(r))
 }
@@ -0,0 +1,67 @@
+package www
+
+import (
+	"errors"
+	"fmt"
+	"media-downloader/internal/config"
+	"media-downloader/internal/media/ytdlp/ytdlptest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewServerLimitsYtdlpRoutes(t *testing.T) {
+	previousLimiter, previousJobs, previousTemplate := clientLimiter, jobManager, filenameTemplate
+	t.Cleanup(func() {
+		clientLimiter, jobManager, filenameTemplate = previousLimiter, previousJobs, previousTemplate
+	})
+	fakeYtdlp(t, ytdlptest.Replay("", "ERROR: [youtube] dQw4w9WgXcQ: Video unavailable\n", errors.New("exit status 1")))
+
+	server, err := NewServer(&config.Config{
+		ClientRateLimit:  1,
+		ClientRateBurst:  1,
+		JobWorkers:       1,
+		JobResultTTL:     time.Hour,
+		FilenameTemplate: "{title}",
+		JSONNaming:       "snake_case",
+		DebugEndpoints:   true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const url = "?url=https://www.youtube.com/watch?v%3DdQw4w9WgXcQ"
+	routes := []string{
+		"/api/quality" + url,
+		"/api/quality/batch",
+		"/api/quality/merged_size" + url + "&video_identifier=137&audio_identifier=140",
+		"/api/info" + url,
+		"/api/download" + url,
+		"/api/jobs",
+		"/api/playlist" + url,
+		"/api/subtitles" + url,
+		"/api/debug/raw" + url,
+	}
+
+	for i, route := range routes {
+		path, _, _ := strings.Cut(route, "?")
+		t.Run(path, func(t *testing.T) {
+			// Every route has its own client so only its own requests count
+			remoteAddr := fmt.Sprintf("192.0.2.%d:1234", i+1)
+
+			for attempt := range 2 {
+				request := httptest.NewRequest(http.MethodGet, route, nil)
+				request.RemoteAddr = remoteAddr
+				recorder := httptest.NewRecorder()
+				server.Handler.ServeHTTP(recorder, request)
+
+				limited := recorder.Code == http.StatusTooManyRequests
+				if limited != (attempt == 1) {
+					t.Errorf("attempt %d got status %d", attempt+1, recorder.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package www
+
+import (
+	"errors"
+	"io"
+)
+
+// Largest number of bytes a single download may send, zero means unlimited
+var maxDownloadBytes uint64
+
+var errDownloadTooLarge = errors.New("download exceeded the maximum size")
+
+// sizeLimitedReader fails once more than limit bytes have been read, for
+// downloads whose size wasn't known up front
+type sizeLimitedReader struct {
+	reader io.Reader
+	limit  uint64
+	read   uint64
+}
+
+func limitDownloadSize(reader io.Reader) io.Reader {
+	if maxDownloadBytes == 0 {
+		return reader
+	}
+	return &sizeLimitedReader{reader: reader, limit: maxDownloadBytes}
+}
+
+func (r *sizeLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += uint64(n)
+	if r.read > r.limit {
+		return 0, errDownloadTooLarge
+	}
+	return n, err
+}
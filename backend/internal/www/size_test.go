@@ -0,0 +1,38 @@
+package www
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLimitDownloadSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   uint64
+		body    string
+		wantErr error
+	}{
+		{name: "unlimited", limit: 0, body: "0123456789"},
+		{name: "below the limit", limit: 20, body: "0123456789"},
+		{name: "exactly the limit", limit: 10, body: "0123456789"},
+		{name: "above the limit", limit: 9, body: "0123456789", wantErr: errDownloadTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			previous := maxDownloadBytes
+			maxDownloadBytes = test.limit
+			t.Cleanup(func() { maxDownloadBytes = previous })
+
+			body, err := io.ReadAll(limitDownloadSize(strings.NewReader(test.body)))
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr == nil && string(body) != test.body {
+				t.Errorf("got %q, want %q", body, test.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package www
+
+import (
+	"fmt"
+	"io"
+	"media-downloader/internal/media"
+	"net/http"
+)
+
+func subtitlesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := ParseQuery(r)
+	urlParam, err := query.Get("url")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing url parameter")
+		return
+	}
+
+	// With a track the subtitles in that language are downloaded instead of
+	// listed. The lang parameter only orders the listing, as everywhere else.
+	if query.Has("track") {
+		subtitleDownloadHandler(w, r, query, urlParam)
+		return
+	}
+
+	mediaInfo, err := media.FetchMedia(requestContext(r, query), urlParam)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	jsonBytes, err := marshalJSON(mediaInfo.Subtitles)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(jsonBytes)))
+	_, err = w.Write(jsonBytes)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+}
+
+func subtitleDownloadHandler(w http.ResponseWriter, r *http.Request, query RequestQuery, urlParam string) {
+	language, err := query.Get("track")
+	if err != nil || language == "" {
+		writeError(w, http.StatusBadRequest, codeMissingParameter, "Missing track parameter")
+		return
+	}
+
+	extension := query.GetOrDefault("format", "")
+	automatic, _ := query.GetBool("automatic")
+
+	mediaInfo, _, extension, reader, err := media.DownloadSubtitle(requestContext(r, query), urlParam, language, extension, automatic)
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+	defer reader.Close()
+
+	filename := SanitizeFilename(fmt.Sprintf("%s.%s", mediaInfo.FileTitle(), language), extension)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", contentDisposition(filename))
+
+	_, err = io.Copy(w, reader)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+}
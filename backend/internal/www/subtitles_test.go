@@ -0,0 +1,60 @@
+package www
+
+import (
+	"encoding/json"
+	"io"
+	"media-downloader/internal/media/info"
+	"media-downloader/internal/media/ytdlp/ytdlptest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSubtitlesHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body tells which track was fetched
+		_, _ = io.WriteString(w, r.URL.Query().Get("lang")+"."+r.URL.Query().Get("fmt"))
+	}))
+	defer server.Close()
+	fixture := ytdlptest.Fixture(t, "youtube_dubbed.json")
+	fakeYtdlp(t, ytdlptest.Replay(strings.ReplaceAll(fixture, "https://www.youtube.com/api/timedtext", server.URL+"/api/timedtext"), "", nil))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantList   bool
+		wantBody   string
+	}{
+		{name: "list", query: "", wantStatus: http.StatusOK, wantList: true},
+		{name: "language preference still lists", query: "&lang=es", wantStatus: http.StatusOK, wantList: true},
+		{name: "download a track", query: "&track=es&format=vtt", wantStatus: http.StatusOK, wantBody: "es-419.vtt"},
+		{name: "track wins over the preference", query: "&lang=es&track=en&format=vtt", wantStatus: http.StatusOK, wantBody: "en.vtt"},
+		{name: "empty track", query: "&track=", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/api/subtitles?url=https://www.youtube.com/watch?v%3DdQw4w9WgXcQ"+test.query, nil)
+			subtitlesHandler(recorder, request)
+
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body.String())
+			}
+			if test.wantList {
+				var subtitles []info.Subtitle
+				if err := json.Unmarshal(recorder.Body.Bytes(), &subtitles); err != nil {
+					t.Fatalf("got %q, want the list of tracks: %v", recorder.Body.String(), err)
+				}
+				if len(subtitles) != 3 {
+					t.Errorf("got %d tracks, want 3", len(subtitles))
+				}
+			}
+			if test.wantBody != "" && recorder.Body.String() != test.wantBody {
+				t.Errorf("got %q, want %q", recorder.Body.String(), test.wantBody)
+			}
+		})
+	}
+}
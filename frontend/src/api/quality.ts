@@ -34,6 +34,12 @@ const mediaInfoSchema = z.object({
   audio_formats: z.array(audioFormatSchema),
 });
 
+// Error body returned by every API endpoint on failure
+const apiErrorSchema = z.object({
+  error: z.string(),
+  code: z.string(),
+});
+
 // Infer types
 export type MediaInfo = z.infer<typeof mediaInfoSchema>;
 export type VideoFormat = z.infer<typeof videoFormatSchema>;
@@ -55,8 +61,9 @@ const fetchQualityInfo: QueryFunction<MediaInfo, QualityQueryKey> = async ({ que
   const response = await fetch(apiUrl);
 
   if (!response.ok) {
-    const errorBody = await response.text().catch(() => null);
-    const errorMessage = errorBody || response.statusText;
+    const errorBody = await response.json().catch(() => null);
+    const parsedError = apiErrorSchema.safeParse(errorBody);
+    const errorMessage = parsedError.success ? parsedError.data.error : response.statusText;
     throw new Error(`Failed to fetch quality data: ${response.status} ${errorMessage}`);
   }
 